@@ -1,17 +1,28 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"github.com/lightless233/enum-subdomain-go/internal"
 	"github.com/lightless233/enum-subdomain-go/pkg"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
 	// 解析命令行参数
-	appArgs, err := enumsubdomain.ParseCLIArgs()
+	cliArgs, err := enumsubdomain.ParseCLICommand()
 	if err != nil {
 		panic(err)
+	}
+
+	// 只输出了帮助或者版本信息
+	if cliArgs.Command == "" {
 		return
 	}
+	appArgs := cliArgs.App
 
 	// 初始化日志系统
 	if appArgs.Debug {
@@ -25,11 +36,66 @@ func main() {
 		logger.Infof("AppArgs: %+v", appArgs.PrettyString())
 	}
 
+	// 监听 Ctrl-C 和 SIGTERM，收到信号后取消扫描流程，等待正在进行的查询完成并写完结果后再退出
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signalChan
+		// 恢复默认的信号处理，再按一次 Ctrl-C 直接强制退出
+		signal.Stop(signalChan)
+		logger.Warn("Received interrupt signal, waiting for running queries to finish. Press Ctrl-C again to force exit.")
+		cancel()
+	}()
+
+	// 提供 Prometheus 指标
+	if cliArgs.MetricsAddr != "" {
+		go func() {
+			if err := enumsubdomain.ServeMetrics(ctx, cliArgs.MetricsAddr); err != nil {
+				logger.Fatalf("Error when serve metrics, error: %+v", err)
+			}
+		}()
+	}
+
+	// HTTP API 模式
+	if cliArgs.Command == enumsubdomain.CommandServe {
+		server := enumsubdomain.NewServer(ctx, cliArgs.Serve)
+		if err := server.Run(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatalf("Error when run API server, error: %+v", err)
+		}
+		return
+	}
+
+	// 循环扫描模式
+	if cliArgs.Command == enumsubdomain.CommandMonitor {
+		monitor, err := enumsubdomain.NewMonitor(appArgs, cliArgs.Monitor)
+		if err != nil {
+			logger.Fatalf("Error when create monitor, error: %+v", err)
+		}
+		if err := monitor.Run(ctx); err != nil {
+			logger.Fatalf("Error when run monitor, error: %+v", err)
+		}
+		return
+	}
+
 	// 创建 App 并执行
 	app := enumsubdomain.NewApp(appArgs)
-	_, err = app.Run()
-	if err != nil {
+	_, err = app.RunContext(ctx)
+	if err != nil && !errors.Is(err, context.Canceled) {
 		logger.Fatalf("Error when run EnumSubdomain, error: %+v", err)
-		return
+	}
+
+	// 在检查参数（例如查询 FOFA 账号）时被中断，扫描还没有开始，没有统计信息
+	summary := app.Summary()
+	if summary == nil {
+		logger.Info("Scan interrupted before start.")
+		_ = logger.Sync()
+		os.Exit(130)
+	}
+	logger.Info(summary.String())
+	if summary.Interrupted {
+		_ = logger.Sync()
+		os.Exit(130)
 	}
 }
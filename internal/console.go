@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"golang.org/x/term"
+	"os"
+	"sync"
+)
+
+// clearLine 回到行首并清除当前行
+const clearLine = "\r\033[K"
+
+// console 标准输出，日志和进度条都通过它输出
+// 设置了状态行（进度条）时，输出日志前先擦掉状态行，输出后再重新画出来，避免两者混在一起
+type console struct {
+	mutex  sync.Mutex
+	status string
+}
+
+// Console 日志写入标准输出时使用
+var Console = &console{}
+
+func (c *console) Write(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.status != "" {
+		_, _ = os.Stdout.WriteString(clearLine)
+	}
+	n, err := os.Stdout.Write(p)
+	if c.status != "" {
+		_, _ = os.Stdout.WriteString(c.status)
+	}
+	return n, err
+}
+
+// Sync 标准输出不需要 Sync，终端上调用 os.Stdout.Sync 会报错
+func (c *console) Sync() error {
+	return nil
+}
+
+// SetStatus 设置并重新绘制状态行
+func (c *console) SetStatus(status string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.status = status
+	_, _ = os.Stdout.WriteString(clearLine + status)
+}
+
+// ClearStatus 清除状态行
+func (c *console) ClearStatus() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.status != "" {
+		_, _ = os.Stdout.WriteString(clearLine)
+		c.status = ""
+	}
+}
+
+// IsTerminal 标准输出是否为终端，不是终端时不显示进度条
+func IsTerminal() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// TerminalWidth 终端的宽度，获取失败时返回 80
+func TerminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return 80
+	}
+	return width
+}
@@ -59,7 +59,7 @@ func InitLogger(debug bool) {
 	}
 	encoder := zapcore.NewConsoleEncoder(encoderConfig)
 
-	syncer := zapcore.NewMultiWriteSyncer(zapcore.AddSync(lumberjackLogger), zapcore.AddSync(os.Stdout))
+	syncer := zapcore.NewMultiWriteSyncer(zapcore.AddSync(lumberjackLogger), Console)
 	var core zapcore.Core
 	if debug {
 		core = zapcore.NewCore(encoder, syncer, zapcore.DebugLevel)
@@ -1,19 +1,35 @@
 package enumsubdomain
 
 import (
+	"context"
 	"fmt"
 	"github.com/lightless233/enum-subdomain-go/internal"
 	"net"
+	"os"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 var logger = internal.GetSugar()
 
 type App struct {
-	args *AppArgs
+	args         *AppArgs
+	stats        *ScanStats
+	summary      *ScanSummary
+	resumed      *Checkpoint
+	checkpointer *Checkpointer
+	outputSpecs  []OutputSpec
+	extraWriters []OutputWriter
+	differ       *Differ
+	diffReport   *DiffReport
+	passive      *PassiveFindings
+
+	// 本次扫描中被动数据源共享的状态，每次扫描开始时重新创建，重复使用 AppArgs 时不会带上之前的额度和收集到的数据
+	passiveRun
 }
 
 func NewApp(args *AppArgs) *App {
@@ -26,28 +42,135 @@ func NewApp(args *AppArgs) *App {
 		}
 	}
 
-	return &App{args: args}
+	return &App{args: args, stats: &ScanStats{}, passive: NewPassiveFindings()}
 }
 
-func (app *App) checkTechnicals() error {
+func (app *App) checkTechnicals(ctx context.Context) error {
 	if app.args.Technicals == nil || len(app.args.Technicals) == 0 {
 		return fmt.Errorf("technical can't be empty")
 	}
 
-	// 如果走 CLI 进来的，不会有这个校验，要再检查一次
-	for _, tech := range app.args.Technicals {
-		tech = strings.ToUpper(strings.TrimSpace(tech))
-		if tech != "D" && tech != "L" && tech != "F" {
-			return fmt.Errorf("technicals argument error, only D, L, F allowed")
+	// 如果不是走 CLI 进来的，不会有这个校验，要再检查一次
+	for idx, tech := range app.args.Technicals {
+		normalized, ok := normalizeTechnical(tech)
+		if !ok {
+			return fmt.Errorf("technicals argument error, only %s allowed", strings.Join(allowedTechnicals(), ", "))
 		}
+		app.args.Technicals[idx] = normalized
 	}
 
 	for _, tech := range app.args.Technicals {
 		if tech == "F" && app.args.FofaToken == "" {
 			return fmt.Errorf("fofa token can't be empty when set 'F' technical")
 		}
+		if info := passiveSourceByTechnical(tech); info != nil && tech != "F" && info.keyFormat != "" && app.args.Credentials[info.name] == "" {
+			return fmt.Errorf("%s credential (%s) can't be empty when set '%s' technical, use the %s env or the %s key of the secrets file",
+				info.name, info.keyFormat, tech, CredentialEnv(info.name), info.name)
+		}
+		if tech == "I" {
+			if len(app.args.ImportFiles) == 0 {
+				return fmt.Errorf("import file can't be empty when set 'I' technical")
+			}
+			for _, filename := range app.args.ImportFiles {
+				if _, err := os.Stat(filename); err != nil {
+					return fmt.Errorf("can't read import file %s: %w", filename, err)
+				}
+			}
+		}
+		if tech == "E" {
+			if len(app.args.ExtractPaths) == 0 {
+				return fmt.Errorf("extract path can't be empty when set 'E' technical")
+			}
+			for _, path := range app.args.ExtractPaths {
+				if _, err := os.Stat(path); err != nil {
+					return fmt.Errorf("can't read extract path %s: %w", path, err)
+				}
+			}
+		}
+		if tech == "P" {
+			if len(app.args.PcapFiles) == 0 {
+				return fmt.Errorf("pcap file can't be empty when set 'P' technical")
+			}
+			for _, filename := range app.args.PcapFiles {
+				if _, err := os.Stat(filename); err != nil {
+					return fmt.Errorf("can't read pcap file %s: %w", filename, err)
+				}
+			}
+		}
+	}
+
+	// FofaEngine 在设置了 token 时就会启动，格式不对时提前报错
+	if app.args.FofaToken != "" && !strings.Contains(app.args.FofaToken, "|") {
+		return fmt.Errorf("fofa token format error, should be email|token")
+	}
+
+	if err := app.checkFofaArgs(); err != nil {
+		return err
+	}
+	return app.checkFofaAccount(ctx)
+}
+
+// checkFofaArgs 检查 FOFA 查询参数，没有设置的参数使用默认值
+func (app *App) checkFofaArgs() error {
+	if strings.TrimSpace(app.args.FofaQuery) == "" {
+		app.args.FofaQuery = DefaultFofaQuery
+	}
+	if len(app.args.FofaFields) == 0 {
+		app.args.FofaFields = append([]string{}, DefaultFofaFields...)
+	}
+	if !slices.Contains(app.args.FofaFields, "host") {
+		return fmt.Errorf("fofa fields must contain host")
+	}
+	if app.args.FofaPageSize == 0 {
+		app.args.FofaPageSize = DefaultFofaPageSize
+	}
+	if app.args.FofaPageSize > maxFofaPageSize {
+		return fmt.Errorf("fofa page size can't be greater than %d", maxFofaPageSize)
+	}
+	if app.args.FofaPages == 0 {
+		app.args.FofaPages = DefaultFofaPages
+	}
+	// FofaEngine 创建客户端失败时会直接退出，这里提前检查 token 和 base url
+	if app.args.FofaToken != "" {
+		if _, err := NewFofaClient(app.args.sourceBaseURL(passiveSourceByTechnical("F")), app.args.FofaToken); err != nil {
+			return err
+		}
+	}
+
+	// 多个目标使用同一个查询语句时，每个目标都会查询到相同的结果
+	if !strings.Contains(app.args.FofaQuery, fofaTargetPlaceholder) {
+		logger.Warnf("FOFA query %s doesn't contain %s, every target will get the same results", app.args.FofaQuery, fofaTargetPlaceholder)
+	}
+	return nil
+}
+
+// checkFofaAccount 使用 F 模式时先查询 FOFA 账号信息，提前发现错误的凭据，并按剩余额度限制查询的页数
+func (app *App) checkFofaAccount(ctx context.Context) error {
+	if !slices.Contains(app.args.Technicals, "F") {
+		return nil
 	}
+	client, err := NewFofaClient(app.args.sourceBaseURL(passiveSourceByTechnical("F")), app.args.FofaToken)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	info, err := client.AccountInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("fofa account check failed: %w", err)
+	}
+	logger.Infof("FOFA account: %s", info)
 
+	pages := info.AffordablePages(int(app.args.FofaPageSize))
+	if pages < 0 {
+		return nil
+	}
+	app.fofaQuota = &fofaQuota{remaining: pages}
+	if planned := len(app.args.Targets) * int(app.args.FofaPages); pages < planned {
+		logger.Warnf("FOFA quota can only afford %d of at most %d pages (%d targets * %d pages), the rest will be skipped.",
+			pages, planned, len(app.args.Targets), app.args.FofaPages)
+	}
 	return nil
 }
 
@@ -66,11 +189,16 @@ func (app *App) checkNameserver() (*DNSClient, error) {
 		logger.Infof("Use default nameservers: %+v", app.args.Nameserver)
 
 	} else {
-		for _, ns := range app.args.Nameserver {
-			parsedIP := net.ParseIP(ns)
-			if parsedIP == nil {
+		// 允许 ip 和 ip:port 两种格式，CLI 传进来的已经补全了 53 端口
+		for idx, ns := range app.args.Nameserver {
+			host, port, err := net.SplitHostPort(ns)
+			if err != nil {
+				host, port = ns, "53"
+			}
+			if net.ParseIP(host) == nil {
 				return nil, fmt.Errorf("nameserver format error: %s", ns)
 			}
+			app.args.Nameserver[idx] = net.JoinHostPort(host, port)
 		}
 	}
 
@@ -93,26 +221,241 @@ func (app *App) checkNameserver() (*DNSClient, error) {
 	return dnsClient, nil
 }
 
+// checkTargets 合并 Target、Targets 和 TargetsFile 中的目标域名，校验、标准化后去重
+func (app *App) checkTargets() error {
+	targets := make([]string, 0, len(app.args.Targets)+1)
+	if app.args.Target != "" {
+		targets = append(targets, app.args.Target)
+	}
+	targets = append(targets, app.args.Targets...)
+
+	if app.args.TargetsFile != "" {
+		fileTargets, err := ReadTargetsFile(app.args.TargetsFile)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, fileTargets...)
+		// 已经读取过了，避免 stdin 被重复读取
+		app.args.TargetsFile = ""
+	}
+
+	app.args.Targets = make([]string, 0, len(targets))
+	for _, raw := range targets {
+		target, err := NormalizeTarget(raw)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(app.args.Targets, target) {
+			app.args.Targets = append(app.args.Targets, target)
+		}
+	}
+
+	if len(app.args.Targets) == 0 {
+		return fmt.Errorf("target can't be empty")
+	}
+	return nil
+}
+
 func (app *App) checkWildcard(dnsClient *DNSClient) error {
-	if dnsClient.CheckDomainWildcard(app.args.Target) {
-		logger.Warnf("Found wildcard, only `F` technical will execute.")
+	app.args.WildcardTargets = make([]string, 0)
+	for _, target := range app.args.Targets {
+		if dnsClient.CheckDomainWildcard(target) {
+			logger.Warnf("Found wildcard for %s, only `F` technical will execute for it.", target)
+			app.args.WildcardTargets = append(app.args.WildcardTargets, target)
+		}
+	}
+
+	// 如果所有目标都存在泛解析，且没有设置被动数据源，直接返回 error
+	if len(app.args.WildcardTargets) == len(app.args.Targets) && !app.args.hasPassiveTechnical() {
+		return fmt.Errorf("found wildcard, only passive source technicals (e.g. `F`) will execute")
+	}
+
+	return nil
+}
+
+// checkOutputs 解析所有的输出目标，OutputFile 会被合并到 Outputs 中
+func (app *App) checkOutputs() error {
+	outputs := make([]string, 0, len(app.args.Outputs)+1)
+	if app.args.OutputFile != "" {
+		outputs = append(outputs, app.args.OutputFile)
+	}
+	outputs = append(outputs, app.args.Outputs...)
+
+	app.outputSpecs = make([]OutputSpec, 0, len(outputs))
+	app.args.Outputs = make([]string, 0, len(outputs))
+	for _, output := range outputs {
+		spec, err := ParseOutputSpec(output, app.args.OutputFormat)
+		if err != nil {
+			return err
+		}
+		for _, existed := range app.outputSpecs {
+			if existed.Path == spec.Path {
+				return fmt.Errorf("output file %s is used more than once", spec.Path)
+			}
+		}
+		app.outputSpecs = append(app.outputSpecs, spec)
+		app.args.Outputs = append(app.args.Outputs, spec.String())
+	}
+	app.args.OutputFile = ""
+
+	return nil
+}
+
+// openOutputWriters 打开所有的输出文件和结果库，并加上通过 AddOutputWriter 添加的 writer
+func (app *App) openOutputWriters(runCtx context.Context) ([]OutputWriter, error) {
+	// 恢复扫描时总是追加到之前的输出文件
+	appendMode := app.args.AppendOutput || app.resumed != nil
+
+	writers := make([]OutputWriter, 0, len(app.outputSpecs)+len(app.extraWriters)+1)
+	closeOpened := func() {
+		for _, opened := range writers {
+			_ = opened.Close()
+		}
+	}
+	for _, spec := range app.outputSpecs {
+		writer, err := NewOutputWriter(spec, appendMode)
+		if err != nil {
+			closeOpened()
+			return nil, err
+		}
+		writers = append(writers, writer)
+	}
+
+	// 结果库记录每次扫描的历史，用于对比多次扫描的变化
+	if app.args.DatabaseFile != "" {
+		store, err := OpenResultStore(app.args.DatabaseFile)
+		if err != nil {
+			closeOpened()
+			return nil, err
+		}
+		if err := store.BeginRun(runCtx, app.args); err != nil {
+			_ = store.Close()
+			closeOpened()
+			return nil, err
+		}
+		logger.Infof("Save results to %s, run id: %d", app.args.DatabaseFile, store.RunID())
+		writers = append(writers, store)
+	}
+
+	return append(writers, app.extraWriters...), nil
+}
 
-		// 如果没有设置 F 模式，直接返回 error
-		if !slices.Contains(app.args.Technicals, "F") {
-			return fmt.Errorf("found wildcard, only `F` technical will execute")
+// AddOutputWriter 添加一个自定义的输出，SDK 调用方可以借此接收实时的扫描结果，Close 会在扫描结束时调用
+func (app *App) AddOutputWriter(writer OutputWriter) {
+	app.extraWriters = append(app.extraWriters, writer)
+}
+
+// checkDiff 加载需要对比的之前的扫描结果
+func (app *App) checkDiff() error {
+	// 通过 SetDiffBaseline 设置了对比的结果
+	if app.args.DiffAgainst == "" {
+		if app.args.DiffOutput != "" && app.differ == nil {
+			return fmt.Errorf("--diff-output needs --diff")
+		}
+		return nil
+	}
+
+	name := app.args.DiffAgainst
+	var baseline []*ResultRecord
+	if run, ok := strings.CutPrefix(app.args.DiffAgainst, "run:"); ok {
+		if app.args.DatabaseFile == "" {
+			return fmt.Errorf("diff against %s needs a result store, use --db", app.args.DiffAgainst)
+		}
+		store, err := OpenResultStore(app.args.DatabaseFile)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = store.Close() }()
+
+		var runID int64
+		if run == "latest" {
+			runID, err = store.LatestRunID(app.args.Targets)
+		} else {
+			runID, err = strconv.ParseInt(run, 10, 64)
+		}
+		if err != nil {
+			return fmt.Errorf("diff run format error: %s, %w", app.args.DiffAgainst, err)
 		}
 
-		app.args.HasWildcard = true
+		// 第一次扫描时没有可以对比的结果，所有的结果都是新增的
+		if runID == 0 {
+			logger.Warnf("No finished run for %v in %s, all results will be reported as new.", app.args.Targets, app.args.DatabaseFile)
+			baseline = make([]*ResultRecord, 0)
+			name = fmt.Sprintf("no previous run in %s", app.args.DatabaseFile)
+		} else if baseline, err = store.RunRecords(runID); err != nil {
+			return err
+		} else {
+			name = fmt.Sprintf("run %d in %s", runID, app.args.DatabaseFile)
+		}
+	} else {
+		records, err := LoadResultRecords(app.args.DiffAgainst)
+		if err != nil {
+			return err
+		}
+		baseline = records
+	}
+
+	logger.Infof("Loaded %d previous results from %s for diff.", len(baseline), name)
+	app.differ = NewDiffer(name, baseline)
+	return nil
+}
+
+// checkResume 如果指定了 resume 文件，从中恢复扫描参数，后续进度继续写回该文件
+func (app *App) checkResume() error {
+	if app.args.ResumeFile == "" {
+		return nil
 	}
 
+	checkpoint, err := LoadCheckpoint(app.args.ResumeFile)
+	if err != nil {
+		return err
+	}
+	if checkpoint.Finished {
+		return fmt.Errorf("scan in checkpoint file %s is already finished", app.args.ResumeFile)
+	}
+
+	app.args.Target = ""
+	app.args.Targets = checkpoint.Targets
+	app.args.TargetsFile = ""
+	app.args.Technicals = checkpoint.Technicals
+	app.args.DictFile = checkpoint.DictFile
+	app.args.BruteLength = checkpoint.BruteLength
+	app.args.OutputFile = ""
+	app.args.Outputs = checkpoint.Outputs
+	if app.args.CheckpointFile == "" {
+		app.args.CheckpointFile = app.args.ResumeFile
+	}
+	app.resumed = checkpoint
+
+	logger.Infof("Resume scan for %v from %s, %d results found before.", checkpoint.Targets, app.args.ResumeFile, len(checkpoint.Found))
 	return nil
 }
 
 // checkArgs 检查指定的 args 是否合法
-func (app *App) checkArgs() error {
+func (app *App) checkArgs(ctx context.Context) error {
+
+	// 从 checkpoint 中恢复参数
+	if err := app.checkResume(); err != nil {
+		return err
+	}
+
+	// 合并所有的目标域名
+	if err := app.checkTargets(); err != nil {
+		return err
+	}
 
 	// 检查 technicals 是否合法
-	if err := app.checkTechnicals(); err != nil {
+	if err := app.checkTechnicals(ctx); err != nil {
+		return err
+	}
+
+	// 检查输出目标
+	if err := app.checkOutputs(); err != nil {
+		return err
+	}
+
+	// 加载需要对比的结果
+	if err := app.checkDiff(); err != nil {
 		return err
 	}
 
@@ -141,39 +484,178 @@ func (app *App) checkArgs() error {
 	return nil
 }
 
+// SetDiffBaseline 设置需要对比的之前的结果，name 用于在报告中标识对比的来源，DiffAgainst 不为空时会覆盖这里的设置
+func (app *App) SetDiffBaseline(name string, baseline []*ResultRecord) {
+	app.differ = NewDiffer(name, baseline)
+}
+
+// DiffReport 返回最近一次 Run 与之前结果的对比，没有设置 DiffAgainst 或 Run 结束前为 nil
+func (app *App) DiffReport() *DiffReport {
+	return app.diffReport
+}
+
+// PassiveFindings 返回只存在于被动数据源中、没有通过 DNS 验证的发现，扫描过程中也可以调用
+func (app *App) PassiveFindings() []*PassiveFinding {
+	return app.passive.List()
+}
+
+// Stats 返回扫描过程中的计数器，可以在扫描进行中读取
+func (app *App) Stats() *ScanStats {
+	return app.stats
+}
+
+// Summary 返回最近一次 Run 的汇总信息，Run 结束前以及检查参数时被中断为 nil
+func (app *App) Summary() *ScanSummary {
+	return app.summary
+}
+
 // Run 真正的程序入口，不管是 CLI 进来的，还是 API 进来的，都会调用这个函数开始执行
 func (app *App) Run() ([]*SubdomainResult, error) {
+	return app.RunContext(context.Background())
+}
+
+// RunContext 与 Run 相同，但 ctx 被取消时会停止生成新任务，等待正在进行的查询完成、
+// 结果写入文件后返回已经获取到的部分结果以及 ctx.Err()
+func (app *App) RunContext(ctx context.Context) ([]*SubdomainResult, error) {
+	startTime := time.Now()
+	app.passiveRun = passiveRun{}
+
 	// 检查参数是否合法
-	if err := app.checkArgs(); err != nil {
+	if err := app.checkArgs(ctx); err != nil {
 		return nil, err
 	}
 
 	// 主 goroutine 同步使用
 	var waitGroup sync.WaitGroup
 
+	// ResultEngine 写入失败时通过 cancel 停止整个扫描流程
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 打开所有的输出，出错时直接返回，避免扫描完才发现结果无法写入
+	writers, err := app.openOutputWriters(runCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 开启 checkpoint 后定期保存进度，保存最终的进度之前需要先停止并等待定期保存退出，
+	// 否则最后一次定期保存可能会用 finished=false 覆盖最终的进度
+	stopCheckpoint := func() {}
+	if app.args.CheckpointFile != "" {
+		app.checkpointer = NewCheckpointer(app.args.CheckpointFile, app.args, app.resumed)
+		interval := app.args.CheckpointInterval
+		if interval <= 0 {
+			interval = 30 * time.Second
+		}
+		stopChan := make(chan struct{})
+		stoppedChan := make(chan struct{})
+		go func() {
+			defer close(stoppedChan)
+			app.checkpointer.run(interval, stopChan)
+		}()
+		stopCheckpoint = func() {
+			close(stopChan)
+			<-stoppedChan
+		}
+	}
+
 	// 创建所有的队列
-	bruteTaskChan := make(chan string, 256)
-	fofaTaskChan := make(chan string, 1)
+	bruteTaskChan := make(chan *bruteTask, 256)
+	passiveTaskChan := make(chan *passiveTask, 1)
+	if app.args.WaybackOutput != "" {
+		app.archivedURLs = NewArchivedURLs()
+	}
+	if app.args.PcapOutput != "" {
+		app.dnsObservations = NewDNSObservations()
+	}
 	resultChan := make(chan *SubdomainResult, 128)
 
+	// 定期采样队列长度，用于 Prometheus 指标
+	metricScansRunning.Inc()
+	defer metricScansRunning.Dec()
+	sampler := newQueueDepthSampler(map[string]func() int{
+		"brute_task": func() int { return len(bruteTaskChan) },
+		"result":     func() int { return len(resultChan) },
+	})
+	samplerStopChan := make(chan struct{})
+	defer close(samplerStopChan)
+	go sampler.run(samplerStopChan)
+
 	// 启动 resultEngine
-	resultEngine := NewResultEngine(app.args, &waitGroup, resultChan)
+	resultEngine := NewResultEngine(app.args, app.stats, app.checkpointer, app.differ, writers, cancel, &waitGroup, resultChan)
 	waitGroup.Add(1)
 	go resultEngine.Run()
 
 	// 启动 engine wrapper
-	engineWrapper := NewEngineWrapper(app.args, &waitGroup, bruteTaskChan, fofaTaskChan, resultChan)
+	engineWrapper := NewEngineWrapper(runCtx, app.args, &app.passiveRun, app.stats, app.checkpointer, app.passive, &waitGroup, bruteTaskChan, passiveTaskChan, resultChan)
 	waitGroup.Add(1)
 	go engineWrapper.Run()
 
 	// 启动 taskBuilder
-	taskBuilderEngine := NewTaskBuilderEngine(app.args, &waitGroup, bruteTaskChan, fofaTaskChan)
+	taskBuilderEngine := NewTaskBuilderEngine(runCtx, app.args, app.stats, app.checkpointer, &waitGroup, bruteTaskChan, passiveTaskChan)
 	waitGroup.Add(1)
 	go taskBuilderEngine.Run()
 
+	// 显示扫描进度
+	var progressStopChan chan struct{}
+	if app.args.Progress {
+		progressStopChan = make(chan struct{})
+		go newProgressReporter(app.stats).run(progressStopChan)
+	}
+
 	waitGroup.Wait()
+	if progressStopChan != nil {
+		close(progressStopChan)
+	}
 
 	// 等待结束后，所有的引擎已经正常退出了，获取 ResultEngine 中的结果
 	subdomains := resultEngine.subdomainResult
+
+	// 保存最终的进度，被中断时可以通过 --resume 继续
+	stopCheckpoint()
+	if err := app.checkpointer.Save(runCtx.Err() == nil); err != nil {
+		logger.Warnf("Error when saving checkpoint %s, error: %+v", app.args.CheckpointFile, err)
+	}
+
+	app.summary = newScanSummary(app.args, app.stats, startTime, runCtx.Err() != nil)
+
+	// 输出与之前结果的对比
+	app.diffReport = app.differ.Finish(app.args.Targets, runCtx.Err() != nil)
+	if app.diffReport != nil {
+		if app.args.FromCLI {
+			logger.Info(app.diffReport.String())
+		}
+		if app.args.DiffOutput != "" {
+			if err := app.diffReport.WriteFile(app.args.DiffOutput); err != nil && resultEngine.err == nil {
+				resultEngine.err = err
+			}
+		}
+	}
+
+	// 单独输出只存在于被动数据源中的发现
+	if app.args.FromCLI && len(app.passive.List()) > 0 {
+		logger.Info(app.passive.String())
+	}
+	if app.args.PassiveOutput != "" {
+		if err := app.passive.WriteFile(app.args.PassiveOutput); err != nil && resultEngine.err == nil {
+			resultEngine.err = err
+		}
+	}
+	if app.args.WaybackOutput != "" {
+		if err := app.archivedURLs.WriteFile(app.args.WaybackOutput); err != nil && resultEngine.err == nil {
+			resultEngine.err = err
+		}
+	}
+	if app.args.PcapOutput != "" {
+		if err := app.dnsObservations.WriteFile(app.args.PcapOutput); err != nil && resultEngine.err == nil {
+			resultEngine.err = err
+		}
+	}
+	if resultEngine.err != nil {
+		return subdomains, resultEngine.err
+	}
+	if err := ctx.Err(); err != nil {
+		return subdomains, err
+	}
 	return subdomains, nil
 }
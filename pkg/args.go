@@ -9,27 +9,129 @@ import (
 	"os"
 	"regexp"
 	"runtime"
+	"slices"
 	"strings"
+	"time"
 )
 
 type AppArgs struct {
-	Target string
+	Target      string
+	Targets     []string // 需要同时扫描的多个目标，会与 Target 合并去重
+	TargetsFile string   // 从文件中按行读取目标，- 表示从 stdin 读取
 
 	Technicals []string
 
 	DictFile    string
 	BruteLength string
 	FofaToken   string
+	Credentials map[string]string // 被动数据源的凭据，key 为数据源名称，例如 fofa
 
-	OutputFile    string
+	FofaQuery    string   // FOFA 查询语句模板，{target} 会被替换为目标，默认为 domain="{target}"
+	FofaFields   []string // FOFA 返回的字段，必须包含 host，默认为 host,ip,port,protocol
+	FofaPageSize uint     // FOFA 每页的结果数量，默认为 100
+	FofaPages    uint     // 每个目标最多查询的 FOFA 页数，默认为 30
+	FofaBaseURL  string   // FOFA API 地址，默认为 https://fofa.info，测试时可以指向本地的模拟服务
+
+	SourceBaseURLs map[string]string // 被动数据源的 API 地址，key 为数据源名称，例如 hunter，测试时可以指向本地的模拟服务
+	PassivePages   uint              // 除 FOFA 以外的被动数据源每个目标最多查询的页数，默认为 10
+	ImportFiles    []string          // I 模式导入的种子文件：域名列表、amass JSON、subfinder JSONL 或者之前输出的结果
+	ExtractPaths   []string          // E 模式扫描的本地文件或目录，例如源码、JS、HAR 和配置文件
+	PcapFiles      []string          // P 模式读取的抓包文件，支持 pcap 和 pcapng 格式
+
+	OutputFile    string   // 单个输出文件，格式由 OutputFormat 指定
+	OutputFormat  string   // 默认的输出格式：csv、jsonl、json、txt，默认为 csv
+	Outputs       []string // 多个输出目标，格式为 format:path，省略 format 时使用 OutputFormat
+	AppendOutput  bool     // 追加到已有的输出文件，而不是覆盖
+	DatabaseFile  string   // SQLite 结果库，保存每次扫描的历史结果
+	DiffAgainst   string   // 与之前的结果对比：之前的输出文件，或者 run:latest、run:<id> 表示结果库中的某次扫描
+	DiffOutput    string   // 对比结果的输出文件，JSON Lines 格式，只包含新增、变化和消失的子域名
+	PassiveOutput string   // 被动发现的输出文件，JSON Lines 格式，包含被动数据源中无法解析、只有 IP 和目标以外的记录
+	WaybackOutput string   // 网页存档中每个域名的历史 URL 的输出文件，JSON Lines 格式，只在 W 模式下使用
+	PcapOutput    string   // 抓包中每个域名的 DNS 解析结果的输出文件，JSON Lines 格式，只在 P 模式下使用
 	TaskCount     uint
 	CheckWildcard bool
 	Nameserver    []string
 	FetchTitle    bool
+	Progress      bool // 在终端上显示进度条，不是终端时定期输出进度日志
+
+	CheckpointFile     string        // 定期保存扫描进度的文件，为空表示不保存
+	CheckpointInterval time.Duration // 保存进度的间隔，默认 30s
+	ResumeFile         string        // 从该文件恢复扫描，会覆盖 target、technicals、dict、brute-length 和 output 参数
+
+	FromCLI         bool // true 表示是从命令行进入的，默认为 false 表示从 SDK 引入
+	Debug           bool
+	WildcardTargets []string // 检测到泛解析的目标，这些目标只执行被动数据源
+}
+
+// hasWildcard 判断目标是否检测到了泛解析
+func (a *AppArgs) hasWildcard(target string) bool {
+	return slices.Contains(a.WildcardTargets, target)
+}
+
+// technicalAliases technical 的全称，被动数据源的名称也可以作为 technical 使用，例如 fofa,hunter
+var technicalAliases = map[string]string{
+	"dict":   "D",
+	"length": "L",
+}
+
+// allowedTechnicals 所有合法的 technical：D、L 以及所有被动数据源
+func allowedTechnicals() []string {
+	technicals := []string{"D", "L"}
+	for _, info := range passiveSourceRegistry {
+		technicals = append(technicals, info.technical)
+	}
+	return technicals
+}
+
+// normalizeTechnical 把 technical 或者它的全称转换为标准格式，不合法时返回 false
+func normalizeTechnical(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if tech, ok := technicalAliases[strings.ToLower(s)]; ok {
+		return tech, true
+	}
+	for _, info := range passiveSourceRegistry {
+		if strings.EqualFold(s, info.name) {
+			return info.technical, true
+		}
+	}
+	tech := strings.ToUpper(s)
+	return tech, slices.Contains(allowedTechnicals(), tech)
+}
+
+// ParseTechnicals 解析 technicals 参数，支持 DLF、D,L,F 和 dict,fofa,hunter 几种写法
+func ParseTechnicals(s string) ([]string, error) {
+	// 如果包含逗号或者是一个全称则按照逗号切分，否则直接切分
+	var parts []string
+	if _, ok := normalizeTechnical(s); ok || strings.Contains(s, ",") {
+		parts = strings.Split(s, ",")
+	} else {
+		parts = strings.Split(s, "")
+	}
 
-	FromCLI     bool // true 表示是从命令行进入的，默认为 false 表示从 SDK 引入
-	Debug       bool
-	HasWildcard bool
+	// 依次检查每个 technical 是否合法
+	technicals := make([]string, 0, len(parts))
+	for _, part := range parts {
+		tech, ok := normalizeTechnical(part)
+		if !ok {
+			return nil, fmt.Errorf("technicals argument error, only %s allowed", strings.Join(allowedTechnicals(), ", "))
+		}
+		technicals = append(technicals, tech)
+	}
+	return technicals, nil
+}
+
+// MarshalJSON 序列化时隐藏所有的凭据
+func (a AppArgs) MarshalJSON() ([]byte, error) {
+	type plainArgs AppArgs
+	redacted := plainArgs(a)
+	redacted.FofaToken = redact(a.FofaToken)
+	if a.Credentials != nil {
+		redacted.Credentials = make(map[string]string, len(a.Credentials))
+		for name, value := range a.Credentials {
+			redacted.Credentials[name] = redact(value)
+		}
+	}
+	return json.Marshal(redacted)
 }
 
 func (a *AppArgs) PrettyString() string {
@@ -39,152 +141,528 @@ func (a *AppArgs) PrettyString() string {
 	return out.String()
 }
 
+// CLI 支持的命令
+const (
+	CommandScan    = "scan"
+	CommandMonitor = "monitor"
+	CommandServe   = "serve"
+)
+
+// CLIArgs 命令行解析的结果，Command 为空表示只输出了帮助或者版本信息，不需要执行
+type CLIArgs struct {
+	Command string
+	App     *AppArgs
+	Monitor *MonitorArgs
+	Serve   *ServeArgs
+
+	MetricsAddr string // 不为空时在该地址上提供 Prometheus 指标
+	ConfigFile  string // 配置文件，为空时使用默认路径下的配置文件
+	SecretsFile string // 凭据文件，为空时使用默认路径下的凭据文件
+	Profile     string // 使用配置文件中的哪个 profile
+}
+
+// ParseCLIArgs 解析扫描命令的参数，只输出了帮助或者版本信息时返回的 AppArgs 中没有目标
+// 需要支持 monitor、serve 子命令时使用 ParseCLICommand
 func ParseCLIArgs() (*AppArgs, error) {
+	cliArgs, err := ParseCLICommand()
+	if err != nil {
+		return nil, err
+	}
+	if cliArgs.Command != "" && cliArgs.Command != CommandScan {
+		return nil, fmt.Errorf("command %s is not supported by ParseCLIArgs, use ParseCLICommand", cliArgs.Command)
+	}
+	return cliArgs.App, nil
+}
 
-	appArgs := &AppArgs{}
+// ParseCLICommand 解析命令行参数，包括 monitor 和 serve 子命令
+func ParseCLICommand() (*CLIArgs, error) {
+
+	cliArgs := &CLIArgs{App: &AppArgs{}, Monitor: &MonitorArgs{}, Serve: &ServeArgs{}}
+	appArgs := cliArgs.App
+	outputs := cli.StringSlice{}
+
+	// 读取配置文件和所有来源的凭据，没有配置文件时 config 为 nil
+	loadConfig := func() (*Config, map[string]string, error) {
+		configFile := cliArgs.ConfigFile
+		if configFile == "" {
+			configFile = DefaultConfigFile()
+		}
+		var config *Config
+		var baseCredentials map[string]string
+		if configFile != "" {
+			var err error
+			if config, err = LoadConfig(configFile); err != nil {
+				return nil, nil, err
+			}
+			baseCredentials = config.Credentials
+		} else if cliArgs.Profile != "" {
+			return nil, nil, fmt.Errorf("--profile needs a config file, use --config")
+		}
+
+		secretsFile := cliArgs.SecretsFile
+		if secretsFile == "" {
+			secretsFile = DefaultSecretsFile()
+		}
+		credentials, err := LoadCredentials(baseCredentials, secretsFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return config, credentials, nil
+	}
+
+	// 执行命令前统一处理扫描参数，命令行中指定的参数优先于配置文件
+	prepareAppArgs := func(context *cli.Context, command string) error {
+		appArgs.FromCLI = true
+		appArgs.Outputs = outputs.Value()
+
+		config, credentials, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if config != nil {
+			if err := config.Apply(appArgs, cliArgs.Profile, context.IsSet); err != nil {
+				return err
+			}
+		}
+		appArgs.applyCredentials(credentials)
+
+		// 没有指定 technicals 时不会触发 flag 的 Action，使用默认值
+		if len(appArgs.Technicals) == 0 {
+			technicals, err := ParseTechnicals(context.String("technicals"))
+			if err != nil {
+				return err
+			}
+			appArgs.Technicals = technicals
+		}
+
+		if appArgs.Target == "" && appArgs.TargetsFile == "" && appArgs.ResumeFile == "" {
+			return fmt.Errorf("target can't be empty, use --target or --targets-file")
+		}
+		cliArgs.Command = command
+		return nil
+	}
 
 	app := &cli.App{
 		Usage: "Enumerate Subdomains",
 		Action: func(context *cli.Context) error {
-			appArgs.FromCLI = true
-			return nil
+			return prepareAppArgs(context, CommandScan)
 		},
 		Version: "0.1.0",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:        "target",
-				Usage:       "target domain",
-				Destination: &appArgs.Target,
-				Aliases:     []string{"t"},
-				Required:    true,
-				Action: func(context *cli.Context, s string) error {
-					// TODO 校验 target 是否为合法的域名
+		Flags:   append(scanFlags(appArgs, &outputs), append(configFlags(cliArgs), metricsFlag(cliArgs))...),
+		Commands: []*cli.Command{
+			{
+				Name:  CommandMonitor,
+				Usage: "Rescan targets on a schedule, diff each run against the previous one and send changes to a webhook",
+				Action: func(context *cli.Context) error {
+					if appArgs.ResumeFile != "" {
+						return fmt.Errorf("--resume can't be used in monitor mode")
+					}
+					if !slices.Contains(WebhookTypes, cliArgs.Monitor.WebhookType) {
+						return fmt.Errorf("webhook type error, only %s allowed", strings.Join(WebhookTypes, ", "))
+					}
+					return prepareAppArgs(context, CommandMonitor)
+				},
+				Flags: append(append(scanFlags(appArgs, &outputs), monitorFlags(cliArgs.Monitor)...), append(configFlags(cliArgs), metricsFlag(cliArgs))...),
+			},
+			{
+				Name:  CommandServe,
+				Usage: "Run an HTTP API server to submit, list, cancel scan jobs and fetch their results",
+				Action: func(context *cli.Context) error {
+					appArgs.FromCLI = true
+					// 任务中没有指定凭据时使用服务端的凭据
+					_, credentials, err := loadConfig()
+					if err != nil {
+						return err
+					}
+					cliArgs.Serve.Credentials = credentials
+					cliArgs.Command = CommandServe
 					return nil
 				},
+				Flags: append(serveFlags(appArgs, cliArgs.Serve), append(configFlags(cliArgs), metricsFlag(cliArgs))...),
 			},
+		},
+	}
 
-			&cli.StringFlag{
-				Name:    "technicals",
-				Aliases: []string{"x"},
-				Usage:   "enumerate technical, available options: D, L, F",
-				Value:   "DL",
-				Action: func(context *cli.Context, s string) error {
-					// 检查 tech 参数是否合法, 如果包含逗号则按照逗号切分，否则直接切分
-					var parts []string
-					if strings.Contains(s, ",") {
-						parts = strings.Split(s, ",")
-					} else {
-						parts = strings.Split(s, "")
-					}
+	if err := app.Run(os.Args); err != nil {
+		return nil, fmt.Errorf("error when run app. %+v", err)
+	}
 
-					// 依次检查每个 technical 是否合法
-					for _, tech := range parts {
-						tech = strings.ToUpper(strings.TrimSpace(tech))
-						if tech != "D" && tech != "L" && tech != "F" {
-							return fmt.Errorf("technicals argument error, only D, L, F allowed")
-						} else {
-							appArgs.Technicals = append(appArgs.Technicals, tech)
-						}
-					}
+	return cliArgs, nil
+}
 
-					return nil
-				},
+// configFlags 配置文件相关的参数
+func configFlags(cliArgs *CLIArgs) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "config",
+			Usage:       "YAML or TOML config file, flags on the command line override values in it",
+			Destination: &cliArgs.ConfigFile,
+			DefaultText: "config.yaml, config.yml or config.toml in the user config dir /enum-subdomain-go",
+		},
+		&cli.StringFlag{
+			Name:        "profile",
+			Usage:       "Use a named profile in the config file",
+			Destination: &cliArgs.Profile,
+		},
+		&cli.StringFlag{
+			Name:        "secrets-file",
+			Usage:       "YAML file with credentials of passive sources, e.g. fofa: email|token, must not be readable by other users",
+			Destination: &cliArgs.SecretsFile,
+			DefaultText: "secrets.yaml in the user config dir /enum-subdomain-go",
+		},
+	}
+}
+
+// metricsFlag 所有命令共用的 Prometheus 指标参数
+func metricsFlag(cliArgs *CLIArgs) cli.Flag {
+	return &cli.StringFlag{
+		Name:        "metrics-addr",
+		Usage:       "Expose Prometheus metrics on this address, e.g. 127.0.0.1:9090, available at /metrics",
+		Destination: &cliArgs.MetricsAddr,
+	}
+}
+
+// monitorFlags monitor 命令额外的参数
+func monitorFlags(monitorArgs *MonitorArgs) []cli.Flag {
+	return []cli.Flag{
+		&cli.DurationFlag{
+			Name:        "interval",
+			Usage:       "Interval between the start of two runs",
+			Destination: &monitorArgs.Interval,
+			Value:       24 * time.Hour,
+		},
+		&cli.UintFlag{
+			Name:        "runs",
+			Usage:       "Stop after this many runs, 0 means run forever",
+			Destination: &monitorArgs.Runs,
+		},
+		&cli.StringFlag{
+			Name:        "webhook",
+			Usage:       "Webhook URL, new, changed and removed subdomains are posted to it after each run",
+			Destination: &monitorArgs.WebhookURL,
+		},
+		&cli.StringFlag{
+			Name:        "webhook-type",
+			Usage:       "Webhook payload type, available options: json, slack, dingtalk",
+			Destination: &monitorArgs.WebhookType,
+			Value:       "json",
+		},
+	}
+}
+
+// serveFlags serve 命令的参数
+func serveFlags(appArgs *AppArgs, serveArgs *ServeArgs) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "listen",
+			Usage:       "Address the API server listens on",
+			Destination: &serveArgs.Listen,
+			Value:       "127.0.0.1:8080",
+		},
+		&cli.UintFlag{
+			Name:        "concurrency",
+			Usage:       "Max number of jobs running at the same time, other jobs wait in the queue",
+			Destination: &serveArgs.Concurrency,
+			Value:       2,
+		},
+		&cli.StringFlag{
+			Name:        "api-token",
+			Usage:       "Require requests to send this token in the header: Authorization: Bearer <token>",
+			Destination: &serveArgs.APIToken,
+		},
+		&cli.StringFlag{
+			Name:        "dict-dir",
+			Usage:       "Directory jobs can pick dict_file from, jobs can't specify a dict file if not set",
+			Destination: &serveArgs.DictDir,
+		},
+		&cli.UintFlag{
+			Name:        "max-jobs",
+			Usage:       "Max number of jobs to keep, the oldest finished jobs and their results are removed when exceeded",
+			Destination: &serveArgs.MaxJobs,
+			Value:       DefaultMaxJobs,
+		},
+		&cli.BoolFlag{
+			Name:        "debug",
+			Usage:       "Debug mode",
+			Destination: &appArgs.Debug,
+			Hidden:      true,
+		},
+	}
+}
+
+// scanFlags 扫描相关的参数，默认命令和 monitor 命令共用
+func scanFlags(appArgs *AppArgs, outputs *cli.StringSlice) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "target",
+			Usage:       "target domain, can be omitted when --targets-file or --resume is set",
+			Destination: &appArgs.Target,
+			Aliases:     []string{"t"},
+			Action: func(context *cli.Context, s string) error {
+				// 校验 target 是否为合法的域名，并转换成标准格式
+				target, err := NormalizeTarget(s)
+				if err != nil {
+					return err
+				}
+				appArgs.Target = target
+				return nil
 			},
+		},
+		&cli.StringFlag{
+			Name:        "targets-file",
+			Usage:       "read target domains from file, one per line, use - to read from stdin",
+			Destination: &appArgs.TargetsFile,
+			Aliases:     []string{"T"},
+		},
 
-			&cli.StringFlag{
-				Name:        "dict-file",
-				Usage:       "dice file path",
-				Destination: &appArgs.DictFile,
-				DefaultText: "empty, use inner dict",
-				Aliases:     []string{"d"},
-				Value:       "",
-				Action: func(context *cli.Context, s string) error {
-					// TODO 校验 dict file 是否存在且可以打开
+		&cli.StringFlag{
+			Name:    "technicals",
+			Aliases: []string{"x"},
+			Usage:   "enumerate technical, available options: D (dict), L (length), F (fofa), H (hunter), Q (quake), Z (zoomeye), V (virustotal), T (securitytrails), S (shodan), W (wayback), I (import), E (extract), P (pcap), e.g. DLF or dict,fofa,hunter",
+			Value:   "DL",
+			Action: func(context *cli.Context, s string) error {
+				technicals, err := ParseTechnicals(s)
+				if err != nil {
+					return err
+				}
+				appArgs.Technicals = technicals
+				return nil
+			},
+		},
+
+		&cli.StringFlag{
+			Name:        "dict-file",
+			Usage:       "dice file path",
+			Destination: &appArgs.DictFile,
+			DefaultText: "empty, use inner dict",
+			Aliases:     []string{"d"},
+			Value:       "",
+			Action: func(context *cli.Context, s string) error {
+				// TODO 校验 dict file 是否存在且可以打开
+				return nil
+			},
+		},
+		&cli.StringFlag{
+			Name:        "brute-length",
+			Usage:       "brute length, e.g. 3 or 1-3",
+			Aliases:     []string{"l"},
+			Destination: &appArgs.BruteLength,
+			Value:       "1-3",
+			Action: func(context *cli.Context, s string) error {
+				// 校验参数是否合法
+				pattern := regexp.MustCompile(`(\d+|\d+-\d+)`)
+				if pattern.MatchString(s) {
 					return nil
-				},
+				} else {
+					return fmt.Errorf("brute length format error")
+				}
 			},
-			&cli.StringFlag{
-				Name:        "brute-length",
-				Usage:       "brute length, e.g. 3 or 1-3",
-				Aliases:     []string{"l"},
-				Destination: &appArgs.BruteLength,
-				Value:       "1-3",
-				Action: func(context *cli.Context, s string) error {
-					// 校验参数是否合法
-					pattern := regexp.MustCompile(`(\d+|\d+-\d+)`)
-					if pattern.MatchString(s) {
-						return nil
-					} else {
-						return fmt.Errorf("brute length format error")
-					}
-				},
+		},
+		&cli.StringFlag{
+			Name:        "fofa-token",
+			Usage:       "fofa token, format: email|token, prefer the ENUM_SUBDOMAIN_FOFA_KEY env or a secrets file to keep it out of shell history",
+			Aliases:     []string{"f"},
+			Destination: &appArgs.FofaToken,
+			Value:       "",
+		},
+		&cli.StringFlag{
+			Name:        "fofa-query",
+			Usage:       "FOFA query template, {target} is replaced with the target, e.g. 'domain=\"{target}\" || cert=\"{target}\"'",
+			Destination: &appArgs.FofaQuery,
+			Value:       DefaultFofaQuery,
+		},
+		&cli.StringFlag{
+			Name:        "fofa-fields",
+			Usage:       "FOFA fields to fetch, use comma to separate multiple fields, must contain host",
+			DefaultText: strings.Join(DefaultFofaFields, ","),
+			Action: func(context *cli.Context, s string) error {
+				appArgs.FofaFields = splitFofaFields(s)
+				return nil
 			},
-			&cli.StringFlag{
-				Name:        "fofa-token",
-				Usage:       "fofa token, format: email|token",
-				Aliases:     []string{"f"},
-				Destination: &appArgs.FofaToken,
-				Value:       "",
+		},
+		&cli.UintFlag{
+			Name:        "fofa-page-size",
+			Usage:       "Number of FOFA results per page",
+			Destination: &appArgs.FofaPageSize,
+			Value:       DefaultFofaPageSize,
+		},
+		&cli.UintFlag{
+			Name:        "fofa-pages",
+			Usage:       "Max FOFA pages to fetch for each target",
+			Destination: &appArgs.FofaPages,
+			Value:       DefaultFofaPages,
+		},
+		&cli.StringFlag{
+			Name:        "fofa-base-url",
+			Usage:       "FOFA API base url, e.g. a local stand-in for testing",
+			Destination: &appArgs.FofaBaseURL,
+			Value:       DefaultFofaBaseURL,
+		},
+		&cli.StringSliceFlag{
+			Name:  "source-base-url",
+			Usage: "API base url of a passive source, format: name=url, e.g. hunter=http://127.0.0.1:8098, can be specified multiple times",
+			Action: func(context *cli.Context, values []string) error {
+				baseURLs, err := parseSourceBaseURLs(values)
+				if err != nil {
+					return err
+				}
+				appArgs.SourceBaseURLs = baseURLs
+				return nil
 			},
-
-			&cli.UintFlag{
-				Name:        "task-count",
-				Usage:       "Task count",
-				Destination: &appArgs.TaskCount,
-				Aliases:     []string{"n"},
-				Value:       uint(2*runtime.NumCPU() + 1),
-				DefaultText: "2 * CPU + 1",
+		},
+		&cli.StringSliceFlag{
+			Name:  "import-file",
+			Usage: "Seed file for the I technical: host list, amass JSON, subfinder JSONL or a previous csv/jsonl/json output, can be specified multiple times",
+			Action: func(context *cli.Context, values []string) error {
+				appArgs.ImportFiles = values
+				return nil
 			},
-			&cli.BoolFlag{
-				Name:        "check-wildcard",
-				Usage:       "Whether to detect wildcard and stop brute mode and dict mode if wildcard is detected.",
-				Destination: &appArgs.CheckWildcard,
-				Value:       true,
+		},
+		&cli.StringSliceFlag{
+			Name:  "extract-path",
+			Usage: "File or directory for the E technical, e.g. source code, JS bundles, HAR captures or config dumps, can be specified multiple times",
+			Action: func(context *cli.Context, values []string) error {
+				appArgs.ExtractPaths = values
+				return nil
+			},
+		},
+		&cli.StringSliceFlag{
+			Name:  "pcap-file",
+			Usage: "Capture file in pcap or pcapng format for the P technical, DNS names of the targets are extracted from it, can be specified multiple times",
+			Action: func(context *cli.Context, values []string) error {
+				appArgs.PcapFiles = values
+				return nil
 			},
-			&cli.StringFlag{
-				Name:  "nameserver",
-				Usage: "Specify DNS servers, use comma to separate multiple DNS",
-				Action: func(context *cli.Context, s string) error {
-					nameservers := strings.Split(s, ",")
-					// 校验 IP 是否合法
-					for _, ip := range nameservers {
-						parsedIP := net.ParseIP(ip)
-						if parsedIP != nil {
-							appArgs.Nameserver = append(appArgs.Nameserver, fmt.Sprintf("%s:53", strings.TrimSpace(ip)))
-						} else {
-							return fmt.Errorf("error when parse nameserver: %s", ip)
-						}
+		},
+		&cli.UintFlag{
+			Name:        "passive-pages",
+			Usage:       "Max pages to fetch for each target from passive sources other than FOFA",
+			Destination: &appArgs.PassivePages,
+			Value:       DefaultPassivePages,
+		},
+
+		&cli.UintFlag{
+			Name:        "task-count",
+			Usage:       "Task count",
+			Destination: &appArgs.TaskCount,
+			Aliases:     []string{"n"},
+			Value:       uint(2*runtime.NumCPU() + 1),
+			DefaultText: "2 * CPU + 1",
+		},
+		&cli.BoolFlag{
+			Name:        "check-wildcard",
+			Usage:       "Whether to detect wildcard and stop brute mode and dict mode if wildcard is detected.",
+			Destination: &appArgs.CheckWildcard,
+			Value:       true,
+		},
+		&cli.StringFlag{
+			Name:  "nameserver",
+			Usage: "Specify DNS servers, use comma to separate multiple DNS",
+			Action: func(context *cli.Context, s string) error {
+				nameservers := strings.Split(s, ",")
+				// 校验 IP 是否合法
+				for _, ip := range nameservers {
+					parsedIP := net.ParseIP(ip)
+					if parsedIP != nil {
+						appArgs.Nameserver = append(appArgs.Nameserver, fmt.Sprintf("%s:53", strings.TrimSpace(ip)))
+					} else {
+						return fmt.Errorf("error when parse nameserver: %s", ip)
 					}
+				}
 
-					return nil
-				},
-			},
-			&cli.BoolFlag{
-				Name:        "fetch-title",
-				Usage:       "Whether to get the page title",
-				Destination: &appArgs.FetchTitle,
-				Value:       false,
-			},
-			&cli.StringFlag{
-				Name:        "output",
-				Usage:       "output filename",
-				Aliases:     []string{"o"},
-				Destination: &appArgs.OutputFile,
-				Value:       "./out.txt",
+				return nil
 			},
-			&cli.BoolFlag{
-				Name:        "debug",
-				Usage:       "Debug mode",
-				Value:       false,
-				Destination: &appArgs.Debug,
-				Hidden:      true,
+		},
+		&cli.BoolFlag{
+			Name:        "fetch-title",
+			Usage:       "Whether to get the page title",
+			Destination: &appArgs.FetchTitle,
+			Value:       false,
+		},
+		&cli.BoolFlag{
+			Name:        "progress",
+			Usage:       "Show a progress bar with ETA, or log the progress periodically when stdout is not a terminal",
+			Destination: &appArgs.Progress,
+			Value:       true,
+		},
+		&cli.StringSliceFlag{
+			Name:        "output",
+			Usage:       "output file, format: [format:]path, can be repeated to write several formats at once",
+			Aliases:     []string{"o"},
+			Destination: outputs,
+			Value:       cli.NewStringSlice("./out.txt"),
+		},
+		&cli.BoolFlag{
+			Name:        "append",
+			Usage:       "Append to existing output files instead of overwriting them",
+			Destination: &appArgs.AppendOutput,
+		},
+		&cli.StringFlag{
+			Name:        "db",
+			Usage:       "SQLite database to keep the history of every run, subdomains get first-seen and last-seen times",
+			Destination: &appArgs.DatabaseFile,
+		},
+		&cli.StringFlag{
+			Name:        "diff",
+			Usage:       "Compare results with a previous output file (csv, jsonl, json, txt) or a stored run: run:latest, run:<id> (needs --db)",
+			Destination: &appArgs.DiffAgainst,
+		},
+		&cli.StringFlag{
+			Name:        "diff-output",
+			Usage:       "Write new, changed and removed subdomains to this file in JSON Lines format, needs --diff",
+			Destination: &appArgs.DiffOutput,
+		},
+		&cli.StringFlag{
+			Name:        "passive-output",
+			Usage:       "Write passive-only findings (unresolved, IP-only and out-of-scope records from passive sources) to this file in JSON Lines format",
+			Destination: &appArgs.PassiveOutput,
+		},
+		&cli.StringFlag{
+			Name:        "wayback-output",
+			Usage:       "Write historic URLs of each host found in the web archive (W technical) to this file in JSON Lines format",
+			Destination: &appArgs.WaybackOutput,
+		},
+		&cli.StringFlag{
+			Name:        "pcap-output",
+			Usage:       "Write DNS names and answers observed in the captures (P technical) to this file in JSON Lines format",
+			Destination: &appArgs.PcapOutput,
+		},
+		&cli.StringFlag{
+			Name:        "checkpoint",
+			Usage:       "Periodically save scan progress to this file, so it can be resumed with --resume",
+			Destination: &appArgs.CheckpointFile,
+		},
+		&cli.DurationFlag{
+			Name:        "checkpoint-interval",
+			Usage:       "Interval between two checkpoints",
+			Destination: &appArgs.CheckpointInterval,
+			Value:       30 * time.Second,
+		},
+		&cli.StringFlag{
+			Name:        "resume",
+			Usage:       "Resume an interrupted scan from the checkpoint file, skip tested names and append to the previous output",
+			Destination: &appArgs.ResumeFile,
+		},
+		&cli.StringFlag{
+			Name:        "format",
+			Usage:       "default output format, available options: csv, jsonl, json, txt",
+			Destination: &appArgs.OutputFormat,
+			Value:       "csv",
+			Action: func(context *cli.Context, s string) error {
+				s = strings.ToLower(strings.TrimSpace(s))
+				if !slices.Contains(OutputFormats, s) {
+					return fmt.Errorf("output format error, only %s allowed", strings.Join(OutputFormats, ", "))
+				}
+				appArgs.OutputFormat = s
+				return nil
 			},
 		},
+		&cli.BoolFlag{
+			Name:        "debug",
+			Usage:       "Debug mode",
+			Value:       false,
+			Destination: &appArgs.Debug,
+			Hidden:      true,
+		},
 	}
-
-	if err := app.Run(os.Args); err != nil {
-		return nil, fmt.Errorf("error when run app. %+v", err)
-	}
-
-	return appArgs, nil
 }
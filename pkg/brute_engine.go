@@ -1,6 +1,7 @@
 package enumsubdomain
 
 import (
+	"context"
 	"fmt"
 	"slices"
 	"sync"
@@ -8,28 +9,36 @@ import (
 )
 
 type BruteEngine struct {
+	ctx       context.Context
 	mainWG    *sync.WaitGroup
 	waitGroup *sync.WaitGroup
 
-	bruteTaskChan  chan string
-	fofaResultChan chan string
-	resultChan     chan *SubdomainResult
+	bruteTaskChan     chan *bruteTask
+	passiveResultChan chan *passiveResult
+	resultChan        chan *SubdomainResult
 
 	channelStatus []bool
 	appArgs       *AppArgs
+	stats         *ScanStats
+	checkpointer  *Checkpointer
+	passive       *PassiveFindings
 }
 
-func NewBruteEngine(appArgs *AppArgs, mainWG *sync.WaitGroup, bruteTaskChan, fofaResultChan chan string, resultChan chan *SubdomainResult) *BruteEngine {
+func NewBruteEngine(ctx context.Context, appArgs *AppArgs, stats *ScanStats, checkpointer *Checkpointer, passive *PassiveFindings, mainWG *sync.WaitGroup, bruteTaskChan chan *bruteTask, passiveResultChan chan *passiveResult, resultChan chan *SubdomainResult) *BruteEngine {
 	var wg sync.WaitGroup
 
 	return &BruteEngine{
-		mainWG:         mainWG,
-		waitGroup:      &wg,
-		bruteTaskChan:  bruteTaskChan,
-		fofaResultChan: fofaResultChan,
-		resultChan:     resultChan,
-		channelStatus:  []bool{true, true},
-		appArgs:        appArgs,
+		ctx:               ctx,
+		mainWG:            mainWG,
+		waitGroup:         &wg,
+		bruteTaskChan:     bruteTaskChan,
+		passiveResultChan: passiveResultChan,
+		resultChan:        resultChan,
+		channelStatus:     []bool{true, true},
+		appArgs:           appArgs,
+		stats:             stats,
+		checkpointer:      checkpointer,
+		passive:           passive,
 	}
 }
 
@@ -45,9 +54,12 @@ func (e *BruteEngine) Run() {
 	e.waitGroup.Wait()
 }
 
-// fetchTaskFromChannel 从多个 channel 中监听任务，收到任务后就返回
-func (e *BruteEngine) fetchTaskFromChannel(timeout time.Duration) string {
-	var domain string
+// fetchTaskFromChannel 从多个 channel 中监听任务，收到任务后就返回域名及其所属的目标
+// 来自 bruteTaskChan 的任务会同时返回 bruteTask，用于完成后更新 checkpoint；来自被动数据源的任务会同时返回 passiveResult
+func (e *BruteEngine) fetchTaskFromChannel(timeout time.Duration) (string, string, *bruteTask, *passiveResult) {
+	var domain, target string
+	var task *bruteTask
+	var passive *passiveResult
 
 	// 同时监听两个 channel，获取任务
 	select {
@@ -58,21 +70,25 @@ func (e *BruteEngine) fetchTaskFromChannel(timeout time.Duration) string {
 			break
 		}
 
-		domain = fmt.Sprintf("%s.%s", v, e.appArgs.Target)
-	case v, opened := <-e.fofaResultChan:
+		domain = fmt.Sprintf("%s.%s", v.name, v.target)
+		target = v.target
+		task = v
+	case v, opened := <-e.passiveResultChan:
 		if !opened {
-			e.fofaResultChan = nil
+			e.passiveResultChan = nil
 			e.channelStatus[1] = false
 			break
 		}
 
-		domain = v
+		domain = v.domain
+		target = v.target
+		passive = v
 	default:
 		time.Sleep(timeout * time.Second)
 		break
 	}
 
-	return domain
+	return domain, target, task, passive
 }
 
 // resolve 执行 DNS 解析，最多重试三次
@@ -96,6 +112,7 @@ func (e *BruteEngine) worker(idx uint) {
 
 	// 每个协程自己维护 client
 	dnsClient := NewDNSClient(e.appArgs.Nameserver)
+	dnsClient.stats = &e.stats.Resolvers
 
 	logger.Debugf("%s start!", tag)
 
@@ -106,39 +123,73 @@ func (e *BruteEngine) worker(idx uint) {
 		}
 
 		// 从监听的channel中获取任务
-		domain := e.fetchTaskFromChannel(1)
+		domain, target, task, passive := e.fetchTaskFromChannel(1)
 		if domain == "" {
 			continue
 		}
 
-		// 执行 DNS 解析
-		result := e.resolve(domain, dnsClient)
-		if result == nil {
-			continue
-		}
-
-		// 提前跳过没有解析记录的结果
-		if len(result.ARecord) == 0 && len(result.CNAMERecord) == 0 {
-			continue
+		// 记录发现该域名的 technical
+		var source string
+		var services []ServiceRecord
+		if task != nil {
+			source = task.cursor.Technical
+		} else {
+			source = passive.source
+			services = passive.services
 		}
 
-		// 最终的扫描结果
-		appResult := &SubdomainResult{}
-		appResult.dnsResult = result
-
-		// 如果设置了获取 HTTP 标题的功能，则在这里去获取
-		if e.appArgs.FetchTitle {
-			httpResult := FetchIndexTitle(domain)
-			appResult.httpResult = httpResult
-		} else {
-			appResult.httpResult = &HTTPResult{}
+		// 标记任务完成，用于记录 checkpoint
+		// 有结果时由 ResultEngine 在结果写入所有输出之后调用，避免 checkpoint 越过还没有写入的结果
+		done := func() {
+			if task != nil {
+				e.checkpointer.taskDone(task)
+			} else {
+				e.checkpointer.passiveTaskDone(source, target)
+			}
 		}
 
-		// 添加到 result channel
-		if len(result.CNAMERecord) != 0 || len(result.ARecord) != 0 {
-			e.resultChan <- appResult
+		// ctx 被取消后上游会停止投递任务并关闭 channel，这里继续把已经排队的任务处理完再退出
+		// 被动数据源中的域名无法解析时作为被动发现记录下来
+		if !e.process(domain, target, source, services, dnsClient, done) {
+			if task == nil {
+				e.passive.add(target, domain, source, PassiveUnresolved, services)
+			}
+			done()
 		}
 	}
 
 	logger.Debugf("%s stop.", tag)
 }
+
+// process 解析一个域名，有解析记录时发送到 result channel 并返回 true，此时由 ResultEngine 调用 done
+func (e *BruteEngine) process(domain, target, source string, services []ServiceRecord, dnsClient *DNSClient, done func()) bool {
+	// 执行 DNS 解析
+	result := e.resolve(domain, dnsClient)
+	e.stats.TasksResolved.Add(1)
+	metricTasksResolved.Inc()
+	if result == nil {
+		return false
+	}
+
+	// 提前跳过没有解析记录的结果
+	if len(result.ARecord) == 0 && len(result.CNAMERecord) == 0 {
+		return false
+	}
+
+	// 最终的扫描结果
+	appResult := &SubdomainResult{target: target, source: source, foundAt: time.Now(), services: services, done: done}
+	appResult.dnsResult = result
+
+	// 如果设置了获取 HTTP 标题的功能，则在这里去获取
+	if e.appArgs.FetchTitle {
+		httpResult := FetchIndexTitle(domain)
+		observeStatus(metricHTTPProbes, httpResult.error != "")
+		appResult.httpResult = httpResult
+	} else {
+		appResult.httpResult = &HTTPResult{}
+	}
+
+	// 添加到 result channel
+	e.resultChan <- appResult
+	return true
+}
@@ -0,0 +1,287 @@
+package enumsubdomain
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// taskCursor 标记一个爆破任务在整个任务序列中的位置
+// Target 是 Targets 中的下标，Stage 是 Technicals 中的下标，Length 只在 L 模式下使用，
+// Offset 在 D 模式下为字典行号，在 L 模式下为该长度 keyspace 中的下标
+type taskCursor struct {
+	Target    int    `json:"target"`
+	Stage     int    `json:"stage"`
+	Technical string `json:"technical"`
+	Length    int    `json:"length,omitempty"`
+	Offset    uint64 `json:"offset"`
+}
+
+// covers 判断 other 是否位于 c 之前（含 c 本身），即 other 在 c 所记录的进度中已经被测试过
+func (c *taskCursor) covers(other taskCursor) bool {
+	if c == nil {
+		return false
+	}
+	if other.Target != c.Target {
+		return other.Target < c.Target
+	}
+	if other.Stage != c.Stage {
+		return other.Stage < c.Stage
+	}
+	if other.Length != c.Length {
+		return other.Length < c.Length
+	}
+	return other.Offset <= c.Offset
+}
+
+// bruteTask TaskBuilderEngine 生成的爆破任务，seq 按生成顺序递增，用于计算已完成的进度
+type bruteTask struct {
+	name   string
+	target string
+	seq    uint64
+	cursor taskCursor
+}
+
+// Checkpoint 写入 state 文件的扫描进度
+type Checkpoint struct {
+	Targets     []string        `json:"targets"`
+	Technicals  []string        `json:"technicals"`
+	DictFile    string          `json:"dict_file"`
+	BruteLength string          `json:"brute_length"`
+	Outputs     []string        `json:"outputs"`
+	Cursor      *taskCursor     `json:"cursor"`       // 该位置及之前的所有爆破任务都已经测试完成，nil 表示还没有完成任何任务
+	FofaDone    []string        `json:"fofa_done"`    // 已经完成 FOFA 查询和验证的目标
+	PassiveDone []string        `json:"passive_done"` // 已经完成查询和验证的其他被动数据源，格式为 technical:target
+	Finished    bool            `json:"finished"`
+	Found       []string        `json:"found"`
+	Records     []*ResultRecord `json:"records,omitempty"` // 已经找到的结果，恢复扫描时用于与之前的结果对比
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// LoadCheckpoint 读取 state 文件
+func LoadCheckpoint(filename string) (*Checkpoint, error) {
+	bs, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't read checkpoint file %s: %w", filename, err)
+	}
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(bs, &checkpoint); err != nil {
+		return nil, fmt.Errorf("checkpoint file %s format error: %w", filename, err)
+	}
+	return &checkpoint, nil
+}
+
+// Checkpointer 追踪扫描进度并定期写入 state 文件
+// 所有方法都允许 nil receiver，未开启 checkpoint 时引擎不需要额外判断
+type Checkpointer struct {
+	mu       sync.Mutex
+	filename string
+	state    *Checkpoint
+	resumed  *Checkpoint // 从 --resume 读取到的进度，TaskBuilderEngine 据此跳过已测试的任务
+
+	// 已完成任务的低水位，next 之前的任务全部完成
+	next uint64
+	done map[uint64]taskCursor
+
+	// 每个数据源和目标的被动查询结果的完成情况，key 为 technical:target
+	passiveFetched map[string]bool
+	passivePending map[string]int64
+}
+
+func NewCheckpointer(filename string, args *AppArgs, resumed *Checkpoint) *Checkpointer {
+	state := &Checkpoint{
+		Targets:     args.Targets,
+		Technicals:  args.Technicals,
+		DictFile:    args.DictFile,
+		BruteLength: args.BruteLength,
+		Outputs:     args.Outputs,
+		FofaDone:    make([]string, 0),
+		PassiveDone: make([]string, 0),
+		Found:       make([]string, 0),
+	}
+	if resumed != nil {
+		state.Cursor = resumed.Cursor
+		state.FofaDone = append(state.FofaDone, resumed.FofaDone...)
+		state.PassiveDone = append(state.PassiveDone, resumed.PassiveDone...)
+		state.Found = append(state.Found, resumed.Found...)
+		state.Records = append(state.Records, resumed.Records...)
+	}
+
+	return &Checkpointer{
+		filename:       filename,
+		state:          state,
+		resumed:        resumed,
+		done:           make(map[uint64]taskCursor),
+		passiveFetched: make(map[string]bool),
+		passivePending: make(map[string]int64),
+	}
+}
+
+// resuming 是否为从 checkpoint 恢复的扫描
+func (c *Checkpointer) resuming() bool {
+	return c != nil && c.resumed != nil
+}
+
+// resumeCursor 返回恢复扫描时需要跳过的进度
+func (c *Checkpointer) resumeCursor() *taskCursor {
+	if !c.resuming() {
+		return nil
+	}
+	return c.resumed.Cursor
+}
+
+// resumePassiveDone 恢复扫描时该目标的被动数据源查询是否已经完成
+func (c *Checkpointer) resumePassiveDone(technical, target string) bool {
+	if !c.resuming() {
+		return false
+	}
+	if technical == "F" {
+		return slices.Contains(c.resumed.FofaDone, target)
+	}
+	return slices.Contains(c.resumed.PassiveDone, passiveKey(technical, target))
+}
+
+// resumeFound 恢复扫描时之前已经找到的域名
+func (c *Checkpointer) resumeFound() []string {
+	if !c.resuming() {
+		return nil
+	}
+	return c.resumed.Found
+}
+
+// taskDone 标记一个爆破任务已完成，并推进低水位
+func (c *Checkpointer) taskDone(task *bruteTask) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.done[task.seq] = task.cursor
+	for {
+		cursor, ok := c.done[c.next]
+		if !ok {
+			break
+		}
+		delete(c.done, c.next)
+		c.state.Cursor = &cursor
+		c.next++
+	}
+}
+
+// passiveKey 被动数据源进度的 key
+func passiveKey(technical, target string) string {
+	return technical + ":" + target
+}
+
+// passiveQueued 记录目标有 n 个被动数据源的域名等待验证，complete 表示该数据源的所有页面都已经获取完成
+func (c *Checkpointer) passiveQueued(technical, target string, n int, complete bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := passiveKey(technical, target)
+	c.passiveFetched[key] = complete
+	c.passivePending[key] += int64(n)
+	c.updatePassiveDone(technical, target)
+}
+
+// passiveTaskDone 标记目标的一个被动数据源的域名已经验证完成
+func (c *Checkpointer) passiveTaskDone(technical, target string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.passivePending[passiveKey(technical, target)]--
+	c.updatePassiveDone(technical, target)
+}
+
+// updatePassiveDone FOFA 的进度保存在 FofaDone 中，兼容之前的 state 文件
+func (c *Checkpointer) updatePassiveDone(technical, target string) {
+	key := passiveKey(technical, target)
+	if !c.passiveFetched[key] || c.passivePending[key] != 0 {
+		return
+	}
+	if technical == "F" {
+		if !slices.Contains(c.state.FofaDone, target) {
+			c.state.FofaDone = append(c.state.FofaDone, target)
+		}
+	} else if !slices.Contains(c.state.PassiveDone, key) {
+		c.state.PassiveDone = append(c.state.PassiveDone, key)
+	}
+}
+
+// resumeRecords 恢复扫描时之前已经找到的结果，旧版本的 state 文件中没有
+func (c *Checkpointer) resumeRecords() []*ResultRecord {
+	if !c.resuming() {
+		return nil
+	}
+	return c.resumed.Records
+}
+
+// addFound 记录一个新找到的结果
+func (c *Checkpointer) addFound(record *ResultRecord) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state.Found = append(c.state.Found, record.Domain)
+	c.state.Records = append(c.state.Records, record)
+}
+
+// Save 把当前进度写入 state 文件，先写临时文件再 rename，避免写到一半时进程退出损坏文件
+func (c *Checkpointer) Save(finished bool) error {
+	if c == nil || c.filename == "" {
+		return nil
+	}
+	c.mu.Lock()
+	c.state.Finished = finished
+	c.state.UpdatedAt = time.Now()
+	bs, err := json.MarshalIndent(c.state, "", "    ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.filename), filepath.Base(c.filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(bs); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.filename)
+}
+
+// run 每隔 interval 保存一次进度，直到 stopChan 被关闭
+func (c *Checkpointer) run(interval time.Duration, stopChan chan struct{}) {
+	if c == nil || c.filename == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Save(false); err != nil {
+				logger.Warnf("Error when saving checkpoint %s, error: %+v", c.filename, err)
+			}
+		case <-stopChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package enumsubdomain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ScanConfig 配置文件中的扫描参数，名称与命令行参数相同，没有出现的参数为 nil，不会覆盖默认值
+type ScanConfig struct {
+	Target             *string           `yaml:"target" toml:"target"`
+	Targets            []string          `yaml:"targets" toml:"targets"`
+	TargetsFile        *string           `yaml:"targets-file" toml:"targets-file"`
+	Technicals         *string           `yaml:"technicals" toml:"technicals"`
+	DictFile           *string           `yaml:"dict-file" toml:"dict-file"`
+	BruteLength        *string           `yaml:"brute-length" toml:"brute-length"`
+	FofaToken          *string           `yaml:"fofa-token" toml:"fofa-token"`
+	FofaQuery          *string           `yaml:"fofa-query" toml:"fofa-query"`
+	FofaFields         []string          `yaml:"fofa-fields" toml:"fofa-fields"`
+	FofaPageSize       *uint             `yaml:"fofa-page-size" toml:"fofa-page-size"`
+	FofaPages          *uint             `yaml:"fofa-pages" toml:"fofa-pages"`
+	FofaBaseURL        *string           `yaml:"fofa-base-url" toml:"fofa-base-url"`
+	SourceBaseURLs     map[string]string `yaml:"source-base-urls" toml:"source-base-urls"`
+	PassivePages       *uint             `yaml:"passive-pages" toml:"passive-pages"`
+	ImportFiles        []string          `yaml:"import-files" toml:"import-files"`
+	ExtractPaths       []string          `yaml:"extract-paths" toml:"extract-paths"`
+	PcapFiles          []string          `yaml:"pcap-files" toml:"pcap-files"`
+	TaskCount          *uint             `yaml:"task-count" toml:"task-count"`
+	CheckWildcard      *bool             `yaml:"check-wildcard" toml:"check-wildcard"`
+	Nameservers        []string          `yaml:"nameservers" toml:"nameservers"`
+	FetchTitle         *bool             `yaml:"fetch-title" toml:"fetch-title"`
+	Progress           *bool             `yaml:"progress" toml:"progress"`
+	Outputs            []string          `yaml:"outputs" toml:"outputs"`
+	Format             *string           `yaml:"format" toml:"format"`
+	Append             *bool             `yaml:"append" toml:"append"`
+	DB                 *string           `yaml:"db" toml:"db"`
+	Checkpoint         *string           `yaml:"checkpoint" toml:"checkpoint"`
+	CheckpointInterval *string           `yaml:"checkpoint-interval" toml:"checkpoint-interval"`
+	Diff               *string           `yaml:"diff" toml:"diff"`
+	DiffOutput         *string           `yaml:"diff-output" toml:"diff-output"`
+	PassiveOutput      *string           `yaml:"passive-output" toml:"passive-output"`
+	WaybackOutput      *string           `yaml:"wayback-output" toml:"wayback-output"`
+	PcapOutput         *string           `yaml:"pcap-output" toml:"pcap-output"`
+}
+
+// Config 配置文件，支持 YAML 和 TOML 两种格式
+//
+// 顶层的扫描参数对所有扫描生效，profiles 中定义的参数会覆盖顶层的参数，命令行参数的优先级最高；
+// credentials 保存各个数据源的凭据，例如 fofa: email|token
+type Config struct {
+	ScanConfig  `yaml:",inline"`
+	Profile     string                `yaml:"profile" toml:"profile"` // 默认使用的 profile
+	Profiles    map[string]ScanConfig `yaml:"profiles" toml:"profiles"`
+	Credentials map[string]string     `yaml:"credentials" toml:"credentials"`
+}
+
+// DefaultConfigFile 返回默认的配置文件路径，按顺序查找用户配置目录下的 config.yaml、config.yml 和 config.toml，都不存在时返回空字符串
+func DefaultConfigFile() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	for _, name := range []string{"config.yaml", "config.yml", "config.toml"} {
+		filename := filepath.Join(configDir, "enum-subdomain-go", name)
+		if _, err := os.Stat(filename); err == nil {
+			return filename
+		}
+	}
+	return ""
+}
+
+// LoadConfig 读取配置文件，扩展名为 .toml 时按 TOML 解析，否则按 YAML 解析
+func LoadConfig(filename string) (*Config, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't read config file %s: %w", filename, err)
+	}
+
+	config := &Config{}
+	if strings.EqualFold(filepath.Ext(filename), ".toml") {
+		// 与 YAML 一样拒绝未知的字段，避免写错的配置项被静默忽略
+		var metadata toml.MetaData
+		if metadata, err = toml.Decode(string(content), config); err == nil {
+			if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+				keys := make([]string, 0, len(undecoded))
+				for _, key := range undecoded {
+					keys = append(keys, key.String())
+				}
+				err = fmt.Errorf("unknown keys: %s", strings.Join(keys, ", "))
+			}
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		// 空文件返回 EOF
+		if err = decoder.Decode(config); errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't parse config file %s: %w", filename, err)
+	}
+	return config, nil
+}
+
+// Apply 把配置应用到 args 上，profile 为空时使用配置文件中指定的默认 profile
+// isSet 用于判断参数是否已经在命令行中指定，已经指定的参数不会被覆盖，为 nil 时表示都没有指定
+// credentials 不在这里处理，需要与凭据文件和环境变量一起通过 LoadCredentials 合并
+func (c *Config) Apply(args *AppArgs, profile string, isSet func(name string) bool) error {
+	if isSet == nil {
+		isSet = func(string) bool { return false }
+	}
+	if profile == "" {
+		profile = c.Profile
+	}
+
+	if err := c.ScanConfig.apply(args, isSet); err != nil {
+		return err
+	}
+	if profile != "" {
+		profileConfig, ok := c.Profiles[profile]
+		if !ok {
+			names := make([]string, 0, len(c.Profiles))
+			for name := range c.Profiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("profile %s not found in config, available profiles: %s", profile, strings.Join(names, ", "))
+		}
+		if err := profileConfig.apply(args, isSet); err != nil {
+			return fmt.Errorf("profile %s: %w", profile, err)
+		}
+	}
+	return nil
+}
+
+// apply 把出现在配置中、且没有在命令行中指定的参数写入 args
+func (c *ScanConfig) apply(args *AppArgs, isSet func(name string) bool) error {
+	setString := func(name string, value *string, field *string) {
+		if value != nil && !isSet(name) {
+			*field = *value
+		}
+	}
+	setBool := func(name string, value *bool, field *bool) {
+		if value != nil && !isSet(name) {
+			*field = *value
+		}
+	}
+
+	// 命令行中指定了任意一种目标时，配置中的目标都不再使用
+	if !isSet("target") && !isSet("targets-file") {
+		if c.Target != nil {
+			args.Target = *c.Target
+		}
+		if c.TargetsFile != nil {
+			args.TargetsFile = *c.TargetsFile
+		}
+		if c.Targets != nil {
+			args.Targets = append([]string{}, c.Targets...)
+		}
+	}
+
+	setString("dict-file", c.DictFile, &args.DictFile)
+	setString("brute-length", c.BruteLength, &args.BruteLength)
+	setString("fofa-token", c.FofaToken, &args.FofaToken)
+	setString("fofa-query", c.FofaQuery, &args.FofaQuery)
+	setString("fofa-base-url", c.FofaBaseURL, &args.FofaBaseURL)
+	setString("format", c.Format, &args.OutputFormat)
+	setString("db", c.DB, &args.DatabaseFile)
+	setString("checkpoint", c.Checkpoint, &args.CheckpointFile)
+	setString("diff", c.Diff, &args.DiffAgainst)
+	setString("diff-output", c.DiffOutput, &args.DiffOutput)
+	setString("passive-output", c.PassiveOutput, &args.PassiveOutput)
+	setString("wayback-output", c.WaybackOutput, &args.WaybackOutput)
+	setString("pcap-output", c.PcapOutput, &args.PcapOutput)
+	setBool("check-wildcard", c.CheckWildcard, &args.CheckWildcard)
+	setBool("fetch-title", c.FetchTitle, &args.FetchTitle)
+	setBool("progress", c.Progress, &args.Progress)
+	setBool("append", c.Append, &args.AppendOutput)
+
+	if c.Technicals != nil && !isSet("technicals") {
+		technicals, err := ParseTechnicals(*c.Technicals)
+		if err != nil {
+			return err
+		}
+		args.Technicals = technicals
+	}
+	if c.TaskCount != nil && !isSet("task-count") {
+		args.TaskCount = *c.TaskCount
+	}
+	if c.FofaFields != nil && !isSet("fofa-fields") {
+		args.FofaFields = append([]string{}, c.FofaFields...)
+	}
+	if c.FofaPageSize != nil && !isSet("fofa-page-size") {
+		args.FofaPageSize = *c.FofaPageSize
+	}
+	if c.FofaPages != nil && !isSet("fofa-pages") {
+		args.FofaPages = *c.FofaPages
+	}
+	if c.SourceBaseURLs != nil && !isSet("source-base-url") {
+		baseURLs := make([]string, 0, len(c.SourceBaseURLs))
+		for name, baseURL := range c.SourceBaseURLs {
+			baseURLs = append(baseURLs, name+"="+baseURL)
+		}
+		parsed, err := parseSourceBaseURLs(baseURLs)
+		if err != nil {
+			return err
+		}
+		if args.SourceBaseURLs == nil {
+			args.SourceBaseURLs = make(map[string]string)
+		}
+		for name, baseURL := range parsed {
+			args.SourceBaseURLs[name] = baseURL
+		}
+	}
+	if c.ImportFiles != nil && !isSet("import-file") {
+		args.ImportFiles = append([]string{}, c.ImportFiles...)
+	}
+	if c.ExtractPaths != nil && !isSet("extract-path") {
+		args.ExtractPaths = append([]string{}, c.ExtractPaths...)
+	}
+	if c.PcapFiles != nil && !isSet("pcap-file") {
+		args.PcapFiles = append([]string{}, c.PcapFiles...)
+	}
+	if c.PassivePages != nil && !isSet("passive-pages") {
+		args.PassivePages = *c.PassivePages
+	}
+	if c.Nameservers != nil && !isSet("nameserver") {
+		args.Nameserver = append([]string{}, c.Nameservers...)
+	}
+	if c.Outputs != nil && !isSet("output") {
+		args.Outputs = append([]string{}, c.Outputs...)
+	}
+	if c.CheckpointInterval != nil && !isSet("checkpoint-interval") {
+		interval, err := time.ParseDuration(*c.CheckpointInterval)
+		if err != nil {
+			return fmt.Errorf("checkpoint-interval format error: %w", err)
+		}
+		args.CheckpointInterval = interval
+	}
+	return nil
+}
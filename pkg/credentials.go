@@ -0,0 +1,142 @@
+package enumsubdomain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// redactedSecret 日志和序列化结果中代替凭据的内容
+const redactedSecret = "******"
+
+// secretQueryParams URL 中需要隐藏的查询参数
+var secretQueryParams = []string{"key", "token", "access_token", "apikey", "api_key", "api-key", "email"}
+
+// CredentialEnv 返回数据源凭据对应的环境变量名，例如 ENUM_SUBDOMAIN_FOFA_KEY
+func CredentialEnv(source string) string {
+	return "ENUM_SUBDOMAIN_" + strings.ToUpper(strings.ReplaceAll(source, "-", "_")) + "_KEY"
+}
+
+// DefaultSecretsFile 返回默认的凭据文件路径，不存在时返回空字符串
+func DefaultSecretsFile() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	filename := filepath.Join(configDir, "enum-subdomain-go", "secrets.yaml")
+	if _, err := os.Stat(filename); err != nil {
+		return ""
+	}
+	return filename
+}
+
+// LoadSecretsFile 读取 YAML 格式的凭据文件，内容为数据源名称到凭据的映射
+// 文件可以被其他用户读写时拒绝读取，需要 chmod 600
+func LoadSecretsFile(filename string) (map[string]string, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't read secrets file %s: %w", filename, err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("secrets file %s is accessible by other users (mode %04o), run: chmod 600 %s",
+			filename, info.Mode().Perm(), filename)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't read secrets file %s: %w", filename, err)
+	}
+	secrets := make(map[string]string)
+	if err := yaml.NewDecoder(bytes.NewReader(content)).Decode(&secrets); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("can't parse secrets file %s: %w", filename, err)
+	}
+	return secrets, nil
+}
+
+// LoadCredentials 合并所有来源的凭据，优先级从低到高依次为：base（配置文件中的 credentials）、凭据文件、环境变量
+// secretsFile 为空时不读取凭据文件
+func LoadCredentials(base map[string]string, secretsFile string) (map[string]string, error) {
+	credentials := make(map[string]string)
+	for name, value := range base {
+		credentials[name] = value
+	}
+
+	if secretsFile != "" {
+		secrets, err := LoadSecretsFile(secretsFile)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range secrets {
+			credentials[name] = value
+		}
+	}
+
+	for _, source := range PassiveSources {
+		if value := os.Getenv(CredentialEnv(source)); value != "" {
+			credentials[source] = value
+		}
+	}
+	// 兼容 FOFA 官方工具使用的环境变量
+	if email, key := os.Getenv("FOFA_EMAIL"), os.Getenv("FOFA_KEY"); email != "" && key != "" && os.Getenv(CredentialEnv("fofa")) == "" {
+		credentials["fofa"] = email + "|" + key
+	}
+	return credentials, nil
+}
+
+// applyCredentials 没有单独指定凭据的数据源使用 credentials 中的凭据
+func (a *AppArgs) applyCredentials(credentials map[string]string) {
+	if a.Credentials == nil {
+		a.Credentials = make(map[string]string)
+	}
+	for name, value := range credentials {
+		if _, ok := a.Credentials[name]; !ok {
+			a.Credentials[name] = value
+		}
+	}
+	if a.FofaToken == "" {
+		a.FofaToken = a.Credentials["fofa"]
+	}
+}
+
+// redact 隐藏非空的凭据
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redactedSecret
+}
+
+// redactURL 隐藏 URL 中的凭据参数和用户信息，用于输出日志
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if parsed.User != nil {
+		parsed.User = url.User(redactedSecret)
+	}
+	query := parsed.Query()
+	for _, name := range secretQueryParams {
+		if query.Has(name) {
+			query.Set(name, redactedSecret)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// redactError 隐藏 HTTP 请求错误中 URL 包含的凭据，net/http 的错误会带上完整的请求地址
+func redactError(err error) error {
+	var urlError *url.Error
+	if errors.As(err, &urlError) {
+		return &url.Error{Op: urlError.Op, URL: redactURL(urlError.URL), Err: urlError.Err}
+	}
+	return err
+}
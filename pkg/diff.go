@@ -0,0 +1,224 @@
+package enumsubdomain
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 结果与上一次扫描相比的状态
+const (
+	DiffNew       = "new"
+	DiffChanged   = "changed"
+	DiffUnchanged = "unchanged"
+	DiffRemoved   = "removed"
+)
+
+// DiffEntry 一个子域名与上一次扫描对比后的结果
+type DiffEntry struct {
+	Status   string        `json:"status"`
+	Target   string        `json:"target"`
+	Domain   string        `json:"domain"`
+	Changes  []string      `json:"changes,omitempty"` // 发生变化的字段，例如 a: [1.1.1.1] -> [2.2.2.2]
+	Current  *ResultRecord `json:"current,omitempty"`
+	Previous *ResultRecord `json:"previous,omitempty"`
+}
+
+// DiffReport 本次扫描与上一次扫描的对比结果
+// 扫描被中断时无法判断哪些子域名消失了，Partial 为 true 且 Removed 为空
+type DiffReport struct {
+	Baseline  string
+	Partial   bool
+	New       []*DiffEntry
+	Changed   []*DiffEntry
+	Unchanged []*DiffEntry
+	Removed   []*DiffEntry
+}
+
+// HasChanges 是否有新增、变化或者消失的子域名
+func (r *DiffReport) HasChanges() bool {
+	return len(r.New) > 0 || len(r.Changed) > 0 || len(r.Removed) > 0
+}
+
+// Entries 返回所有新增、变化和消失的子域名，不包含没有变化的
+func (r *DiffReport) Entries() []*DiffEntry {
+	entries := make([]*DiffEntry, 0, len(r.New)+len(r.Changed)+len(r.Removed))
+	entries = append(entries, r.New...)
+	entries = append(entries, r.Changed...)
+	return append(entries, r.Removed...)
+}
+
+func (r *DiffReport) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Diff against %s: new: %d, changed: %d, unchanged: %d, removed: %d",
+		r.Baseline, len(r.New), len(r.Changed), len(r.Unchanged), len(r.Removed))
+	if r.Partial {
+		builder.WriteString(" (scan interrupted, removed hosts not checked)")
+	}
+	for _, entry := range r.Entries() {
+		fmt.Fprintf(&builder, "\n  [%s] %s", entry.Status, entry.Domain)
+		if len(entry.Changes) > 0 {
+			fmt.Fprintf(&builder, " %s", strings.Join(entry.Changes, "; "))
+		}
+	}
+	return builder.String()
+}
+
+// WriteFile 把新增、变化和消失的子域名以 JSON Lines 格式写入文件
+func (r *DiffReport) WriteFile(filename string) error {
+	fp, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("can't open diff output file %s: %w", filename, err)
+	}
+	for _, entry := range r.Entries() {
+		bs, err := marshalRecord(entry)
+		if err != nil {
+			_ = fp.Close()
+			return err
+		}
+		if _, err := fp.Write(append(bs, '\n')); err != nil {
+			_ = fp.Close()
+			return err
+		}
+	}
+	return fp.Close()
+}
+
+// Differ 把本次扫描的结果与之前的结果逐个对比，所有方法都可以在 nil 上调用
+type Differ struct {
+	mutex    sync.Mutex
+	baseline map[string]*ResultRecord
+	seen     map[string]bool
+	report   *DiffReport
+}
+
+// NewDiffer 使用之前的扫描结果创建 Differ，name 用于在报告中标识对比的来源
+func NewDiffer(name string, baseline []*ResultRecord) *Differ {
+	differ := &Differ{
+		baseline: make(map[string]*ResultRecord, len(baseline)),
+		seen:     make(map[string]bool),
+		report:   &DiffReport{Baseline: name},
+	}
+	for _, record := range baseline {
+		differ.baseline[strings.ToLower(strings.TrimRight(record.Domain, "."))] = record
+	}
+	return differ
+}
+
+// markSeen 恢复扫描时，之前已经找到的结果不会再经过 ResultEngine，标记为已出现避免被当成消失
+func (d *Differ) markSeen(domains []string) {
+	if d == nil {
+		return
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, domain := range domains {
+		d.seen[domain] = true
+	}
+}
+
+// Compare 对比一个结果，返回它的状态
+func (d *Differ) Compare(record *ResultRecord) *DiffEntry {
+	if d == nil {
+		return nil
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.seen[record.Domain] = true
+	entry := &DiffEntry{Target: record.Target, Domain: record.Domain, Current: record}
+
+	previous, ok := d.baseline[record.Domain]
+	if !ok {
+		entry.Status = DiffNew
+		d.report.New = append(d.report.New, entry)
+		return entry
+	}
+
+	entry.Previous = previous
+	entry.Changes = compareRecords(previous, record)
+	if len(entry.Changes) > 0 {
+		entry.Status = DiffChanged
+		d.report.Changed = append(d.report.Changed, entry)
+	} else {
+		entry.Status = DiffUnchanged
+		d.report.Unchanged = append(d.report.Unchanged, entry)
+	}
+	return entry
+}
+
+// Finish 结束对比，之前出现过但本次没有找到的子域名记为消失
+// 只检查属于本次扫描目标的子域名，扫描被中断时不检查
+func (d *Differ) Finish(targets []string, interrupted bool) *DiffReport {
+	if d == nil {
+		return nil
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.report.Partial = interrupted
+	if !interrupted {
+		for domain, previous := range d.baseline {
+			if d.seen[domain] || !belongsToTargets(domain, targets) {
+				continue
+			}
+			d.report.Removed = append(d.report.Removed, &DiffEntry{
+				Status: DiffRemoved, Target: previous.Target, Domain: domain, Previous: previous,
+			})
+		}
+		sort.Slice(d.report.Removed, func(i, j int) bool {
+			return d.report.Removed[i].Domain < d.report.Removed[j].Domain
+		})
+	}
+	return d.report
+}
+
+// belongsToTargets 判断域名是否属于某个扫描目标
+func belongsToTargets(domain string, targets []string) bool {
+	for _, target := range targets {
+		if domain == target || strings.HasSuffix(domain, "."+target) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareRecords 对比 A、CNAME 记录以及 HTTP 状态码和标题，只有两次都探测过 HTTP 时才对比 HTTP 的结果
+// 之前的结果没有解析记录时（例如每行一个域名的 txt 结果）不对比 A 和 CNAME
+func compareRecords(previous, current *ResultRecord) []string {
+	changes := make([]string, 0)
+	if len(previous.A) > 0 || len(previous.CNAME) > 0 {
+		if !sameRecords(previous.A, current.A) {
+			changes = append(changes, fmt.Sprintf("a: %v -> %v", previous.A, current.A))
+		}
+		if !sameRecords(previous.CNAME, current.CNAME) {
+			changes = append(changes, fmt.Sprintf("cname: %v -> %v", previous.CNAME, current.CNAME))
+		}
+	}
+	if previous.HTTP != nil && current.HTTP != nil {
+		if previous.HTTP.StatusCode != current.HTTP.StatusCode {
+			changes = append(changes, fmt.Sprintf("status: %d -> %d", previous.HTTP.StatusCode, current.HTTP.StatusCode))
+		}
+		if previous.HTTP.Title != current.HTTP.Title {
+			changes = append(changes, fmt.Sprintf("title: %s -> %s",
+				strconv.Quote(previous.HTTP.Title), strconv.Quote(current.HTTP.Title)))
+		}
+	}
+	return changes
+}
+
+// sameRecords 忽略顺序比较两组解析记录
+func sameRecords(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := slices.Clone(a)
+	sortedB := slices.Clone(b)
+	slices.Sort(sortedA)
+	slices.Sort(sortedB)
+	return slices.Equal(sortedA, sortedB)
+}
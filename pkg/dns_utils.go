@@ -1,6 +1,7 @@
 package enumsubdomain
 
 import (
+	"fmt"
 	"github.com/miekg/dns"
 	"math/rand"
 	"slices"
@@ -16,6 +17,7 @@ type DNSResolveResult struct {
 type DNSClient struct {
 	client      *dns.Client
 	nameservers []string
+	stats       *ResolverStats // 不为空时记录每个 nameserver 的查询情况
 }
 
 func NewDNSClient(ns []string) *DNSClient {
@@ -60,11 +62,11 @@ func (d *DNSClient) RemoveUnconnectedNS() ([]string, []string) {
 }
 
 func (d *DNSClient) CheckDomainWildcard(domain string) bool {
-	domains := []string{"this-domain-will-never-exist", "neither-will-this-one", RandString(8)}
-	wildcardResults := make([]bool, 0, len(domains))
+	labels := []string{"this-domain-will-never-exist", "neither-will-this-one", RandString(8)}
+	wildcardResults := make([]bool, 0, len(labels))
 
-	for _, domain := range domains {
-		result, err := d.DoDNSResolve(domain)
+	for _, label := range labels {
+		result, err := d.DoDNSResolve(fmt.Sprintf("%s.%s", label, domain))
 		if err != nil {
 			continue
 		}
@@ -91,7 +93,9 @@ func (d *DNSClient) DoDNSResolve(domain string) (*DNSResolveResult, error) {
 
 	// 每次请求的时候，从提供的 ns 中随机取一个
 	ns := d.nameservers[rand.Intn(len(d.nameservers))]
-	response, _, err := d.client.Exchange(&msg, ns)
+	response, rtt, err := d.client.Exchange(&msg, ns)
+	d.stats.record(ns, rtt, err)
+	observeDNSQuery(ns, response, rtt, err)
 
 	if err != nil {
 		return nil, err
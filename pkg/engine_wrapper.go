@@ -1,27 +1,40 @@
 package enumsubdomain
 
-import "sync"
+import (
+	"context"
+	"sync"
+)
 
 type EngineWrapper struct {
+	ctx       context.Context
 	mainWG    *sync.WaitGroup
 	waitGroup *sync.WaitGroup
 
-	bruteTaskChan chan string
-	fofaTaskChan  chan string
-	resultChan    chan *SubdomainResult
+	bruteTaskChan   chan *bruteTask
+	passiveTaskChan chan *passiveTask
+	resultChan      chan *SubdomainResult
 
-	appArgs *AppArgs
+	appArgs      *AppArgs
+	run          *passiveRun
+	stats        *ScanStats
+	checkpointer *Checkpointer
+	passive      *PassiveFindings
 }
 
-func NewEngineWrapper(appArgs *AppArgs, mainWG *sync.WaitGroup, bruteTaskChan, fofaTaskChan chan string, resultChan chan *SubdomainResult) *EngineWrapper {
+func NewEngineWrapper(ctx context.Context, appArgs *AppArgs, run *passiveRun, stats *ScanStats, checkpointer *Checkpointer, passive *PassiveFindings, mainWG *sync.WaitGroup, bruteTaskChan chan *bruteTask, passiveTaskChan chan *passiveTask, resultChan chan *SubdomainResult) *EngineWrapper {
 	var wg sync.WaitGroup
 	return &EngineWrapper{
-		mainWG:        mainWG,
-		waitGroup:     &wg,
-		bruteTaskChan: bruteTaskChan,
-		fofaTaskChan:  fofaTaskChan,
-		resultChan:    resultChan,
-		appArgs:       appArgs,
+		ctx:             ctx,
+		mainWG:          mainWG,
+		waitGroup:       &wg,
+		bruteTaskChan:   bruteTaskChan,
+		passiveTaskChan: passiveTaskChan,
+		resultChan:      resultChan,
+		appArgs:         appArgs,
+		run:             run,
+		stats:           stats,
+		checkpointer:    checkpointer,
+		passive:         passive,
 	}
 }
 
@@ -31,17 +44,17 @@ func (wrapper *EngineWrapper) Run() {
 		close(wrapper.resultChan)
 	}()
 
-	// 这个 channel 只在 fofaEngine 和 bruteEngine 中使用，不需要暴露出去
-	fofaResultChan := make(chan string, 128)
+	// 这个 channel 只在 passiveEngine 和 bruteEngine 中使用，不需要暴露出去
+	passiveResultChan := make(chan *passiveResult, 128)
 
-	// 启动 dns engine 和 fofa engine
-	bruteEngine := NewBruteEngine(wrapper.appArgs, wrapper.waitGroup, wrapper.bruteTaskChan, fofaResultChan, wrapper.resultChan)
+	// 启动 dns engine 和 passive engine
+	bruteEngine := NewBruteEngine(wrapper.ctx, wrapper.appArgs, wrapper.stats, wrapper.checkpointer, wrapper.passive, wrapper.waitGroup, wrapper.bruteTaskChan, passiveResultChan, wrapper.resultChan)
 	wrapper.waitGroup.Add(1)
 	go bruteEngine.Run()
 
-	fofaEngine := NewFofaEngine(wrapper.appArgs, wrapper.waitGroup, wrapper.fofaTaskChan, fofaResultChan)
+	passiveEngine := NewPassiveEngine(wrapper.ctx, wrapper.appArgs, wrapper.run, wrapper.checkpointer, wrapper.passive, wrapper.waitGroup, wrapper.passiveTaskChan, passiveResultChan)
 	wrapper.waitGroup.Add(1)
-	go fofaEngine.Run()
+	go passiveEngine.Run()
 
 	// 等待子引擎结束
 	wrapper.waitGroup.Wait()
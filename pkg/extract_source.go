@@ -0,0 +1,182 @@
+package enumsubdomain
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// maxExtractFileSize 超过这个大小的文件不扫描，避免读入大量的二进制文件
+const maxExtractFileSize = 256 * 1024 * 1024
+
+// extractSkipDirs 扫描目录时跳过的目录
+var extractSkipDirs = []string{".git", ".svn", ".hg"}
+
+// hostPattern 返回匹配目标子域名的正则表达式，只匹配目标的子域名，不匹配目标本身
+func hostPattern(target string) *regexp.Regexp {
+	return regexp.MustCompile(`[a-z0-9][a-z0-9.-]*\.` + regexp.QuoteMeta(target))
+}
+
+// extractHosts 从转换为小写的内容中提取目标的子域名，按出现的顺序返回，已经去重
+func extractHosts(content []byte, target string, pattern *regexp.Regexp) []string {
+	hosts := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, loc := range pattern.FindAllIndex(content, -1) {
+		start, end := loc[0], loc[1]
+
+		// 后面还有域名字符时，是其他域名的一部分，例如 www.example.com.evil.com
+		if end < len(content) && isHostChar(content[end]) {
+			continue
+		}
+		if end+1 < len(content) && content[end] == '.' && isHostChar(content[end+1]) {
+			continue
+		}
+
+		// 去掉 URL 编码和 JSON 转义带来的前缀，例如 %2Fwww.example.com、/www.example.com、\nwww.example.com
+		host := string(content[start:end])
+		if start > 0 {
+			switch content[start-1] {
+			case '%':
+				host = strings.TrimPrefix(strings.TrimPrefix(host, "25"), "2f")
+			case '\\':
+				if strings.HasPrefix(host, "u002f") || strings.HasPrefix(host, "x2f") {
+					host = host[strings.Index(host, "2f")+2:]
+				} else if strings.IndexByte("nrt", host[0]) >= 0 {
+					host = host[1:]
+				}
+			}
+		}
+
+		host = strings.TrimLeft(host, ".-")
+		if !validHostname(host) || !inScope(host, target) || host == target || seen[host] {
+			continue
+		}
+		seen[host] = true
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// isHostChar 判断是否为域名中可以出现的字符（不包括点）
+func isHostChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+}
+
+// validHostname 检查域名的每一段都不为空、不超过 63 个字符，且不以 - 开头或结尾
+func validHostname(host string) bool {
+	if len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// ExtractSource 从本地的源码、JS、HAR 和文本文件中提取目标的子域名，再通过 DNS 验证
+// 所有文件只读取一次，同时匹配所有的目标
+type ExtractSource struct {
+	paths   []string
+	targets []string
+
+	once  sync.Once
+	hosts map[string][]string // 每个目标提取到的子域名
+	err   error
+}
+
+func newExtractSource(args *AppArgs, _ *passiveRun, _ string) (PassiveSource, error) {
+	return &ExtractSource{paths: args.ExtractPaths, targets: args.Targets}, nil
+}
+
+func (s *ExtractSource) Name() string {
+	return "extract"
+}
+
+func (s *ExtractSource) MaxPages() int {
+	return 1
+}
+
+func (s *ExtractSource) Search(ctx context.Context, target string, _ int) (*PassivePage, error) {
+	s.once.Do(func() { s.hosts, s.err = s.scan(ctx) })
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	result := &PassivePage{Hosts: make([]PassiveHost, 0, len(s.hosts[target]))}
+	for _, host := range s.hosts[target] {
+		result.Hosts = append(result.Hosts, PassiveHost{Host: host})
+	}
+	return result, nil
+}
+
+// scan 遍历所有的文件和目录，提取每个目标的子域名
+func (s *ExtractSource) scan(ctx context.Context) (map[string][]string, error) {
+	patterns := make(map[string]*regexp.Regexp, len(s.targets))
+	for _, target := range s.targets {
+		patterns[target] = hostPattern(target)
+	}
+
+	hosts := make(map[string][]string, len(s.targets))
+	seen := make(map[string]bool)
+	files := 0
+	for _, root := range s.paths {
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				logger.Warnf("Error when walk %s: %+v", path, err)
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if entry.IsDir() {
+				if path != root && slices.Contains(extractSkipDirs, entry.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			if info, err := entry.Info(); err != nil || info.Size() > maxExtractFileSize {
+				logger.Warnf("Skip %s, can't stat or larger than %d bytes.", path, maxExtractFileSize)
+				return nil
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				logger.Warnf("Error when read %s: %+v", path, err)
+				return nil
+			}
+			content = bytes.ToLower(content)
+			files++
+			for _, target := range s.targets {
+				// 先判断是否包含目标，大部分文件不需要执行正则
+				if !bytes.Contains(content, []byte(target)) {
+					continue
+				}
+				found := extractHosts(content, target, patterns[target])
+				logger.Debugf("Extracted %d hosts of %s from %s", len(found), target, path)
+				for _, host := range found {
+					if key := target + "|" + host; !seen[key] {
+						seen[key] = true
+						hosts[target] = append(hosts[target], host)
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	logger.Infof("Scanned %d files in %s.", files, strings.Join(s.paths, ", "))
+	return hosts, nil
+}
@@ -0,0 +1,315 @@
+package enumsubdomain
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/bytedance/sonic"
+	"net/http"
+	netURL "net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultFofaBaseURL FOFA API 的默认地址
+const DefaultFofaBaseURL = "https://fofa.info"
+
+// FOFA 查询参数的默认值
+const (
+	DefaultFofaQuery    = `domain="{target}"`
+	DefaultFofaPageSize = 100
+	DefaultFofaPages    = 30
+
+	// fofaTargetPlaceholder 查询语句模板中代表目标的占位符
+	fofaTargetPlaceholder = "{target}"
+	// maxFofaPageSize FOFA 接口允许的最大分页大小
+	maxFofaPageSize = 10000
+)
+
+// DefaultFofaFields 默认获取的 FOFA 字段，ip、port 和 protocol 会保存到结果中
+var DefaultFofaFields = []string{"host", "ip", "port", "protocol"}
+
+// fofaQuota 所有目标共享的 FOFA 页数额度，为 nil 时不限制
+type fofaQuota struct {
+	mutex     sync.Mutex
+	remaining int
+}
+
+// take 消耗一页的额度，额度用完时返回 false
+func (q *fofaQuota) take() bool {
+	if q == nil {
+		return true
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.remaining <= 0 {
+		return false
+	}
+	q.remaining--
+	return true
+}
+
+// splitFofaFields 按逗号切分 FOFA 字段，去掉空白和空字段
+func splitFofaFields(s string) []string {
+	fields := make([]string, 0)
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.ToLower(strings.TrimSpace(field)); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// buildFofaQuery 把查询语句模板中的 {target} 替换为目标
+func buildFofaQuery(template, target string) string {
+	return strings.ReplaceAll(template, fofaTargetPlaceholder, target)
+}
+
+// FofaRow 一条 FOFA 查询结果，按请求的 fields 顺序保存各个字段
+type FofaRow []string
+
+// UnmarshalJSON 只请求一个字段时每条结果是字符串而不是数组，这里统一转换为数组
+func (r *FofaRow) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := sonic.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	values, ok := value.([]any)
+	if !ok {
+		values = []any{value}
+	}
+
+	row := make(FofaRow, 0, len(values))
+	for _, v := range values {
+		switch v := v.(type) {
+		case nil:
+			row = append(row, "")
+		case string:
+			row = append(row, v)
+		default:
+			row = append(row, fmt.Sprint(v))
+		}
+	}
+	*r = row
+	return nil
+}
+
+// FofaSearchResponse FOFA search/all 接口的返回结果
+//
+// 请求结果样例（fields=host,ip,port,protocol）：
+//
+//	{
+//	    "error": false,
+//	    "consumed_fpoint": 0,
+//	    "required_fpoints": 0,
+//	    "size": 11,
+//	    "page": 1,
+//	    "mode": "extended",
+//	    "query": "domain=\"lightless.me\"",
+//	    "results": [
+//	        ["c1.lightless.me", "43.129.25.182", "80", "http"],
+//	        ["https://c1.lightless.me", "43.129.25.182", "443", "https"],
+//	        ["lightless.me:53", "43.129.25.182", "53", "dns"],
+//	        ["ss.lightless.me:10022", "216.24.176.101", "10022", "ssh"]
+//	    ]
+//	}
+type FofaSearchResponse struct {
+	Error          bool      `json:"error"`
+	ErrMsg         string    `json:"errmsg"`
+	ConsumedFpoint int       `json:"consumed_fpoint"`
+	Size           int       `json:"size"` // 查询到的结果总数
+	Page           int       `json:"page"`
+	Mode           string    `json:"mode"`
+	Query          string    `json:"query"`
+	Results        []FofaRow `json:"results"`
+}
+
+// FofaAccountInfo FOFA info/my 接口返回的账号信息，剩余额度字段没有返回时为 nil
+type FofaAccountInfo struct {
+	Error          bool   `json:"error"`
+	ErrMsg         string `json:"errmsg"`
+	Email          string `json:"email"`
+	Username       string `json:"username"`
+	IsVIP          bool   `json:"isvip"`
+	VIPLevel       int    `json:"vip_level"`
+	FofaPoint      int    `json:"fofa_point"`
+	RemainAPIQuery *int   `json:"remain_api_query"` // 今天剩余的查询次数，每页消耗一次
+	RemainAPIData  *int   `json:"remain_api_data"`  // 今天剩余可以获取的数据条数
+}
+
+// AffordablePages 按每页 pageSize 条计算剩余额度还能查询的页数，额度未知时返回 -1
+func (info *FofaAccountInfo) AffordablePages(pageSize int) int {
+	pages := -1
+	if info.RemainAPIQuery != nil {
+		pages = max(*info.RemainAPIQuery, 0)
+	}
+	if info.RemainAPIData != nil && pageSize > 0 {
+		// 最后一页不满时也可以查询
+		dataPages := max((*info.RemainAPIData+pageSize-1)/pageSize, 0)
+		if pages < 0 || dataPages < pages {
+			pages = dataPages
+		}
+	}
+	return pages
+}
+
+// String 账号信息的描述，用于输出日志
+func (info *FofaAccountInfo) String() string {
+	quota := func(value *int) string {
+		if value == nil {
+			return "unknown"
+		}
+		return strconv.Itoa(*value)
+	}
+	return fmt.Sprintf("%s (%s), vip: %t, vip level: %d, F points: %d, remaining queries: %s, remaining data: %s",
+		info.Username, info.Email, info.IsVIP, info.VIPLevel, info.FofaPoint, quota(info.RemainAPIQuery), quota(info.RemainAPIData))
+}
+
+// FofaClient FOFA API 客户端，遇到 429 和 5xx 时按指数退避重试
+type FofaClient struct {
+	baseURL   string
+	email     string
+	key       string
+	requester *apiRequester
+}
+
+// NewFofaClient 创建 FOFA 客户端，token 的格式为 email|key，baseURL 为空时使用 DefaultFofaBaseURL
+func NewFofaClient(baseURL, token string) (*FofaClient, error) {
+	email, key, found := strings.Cut(token, "|")
+	if !found {
+		return nil, fmt.Errorf("fofa token format error, should be email|token")
+	}
+	if baseURL == "" {
+		baseURL = DefaultFofaBaseURL
+	}
+	if _, err := netURL.ParseRequestURI(baseURL); err != nil {
+		return nil, fmt.Errorf("fofa base url error: %w", err)
+	}
+
+	return &FofaClient{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		email:     email,
+		key:       key,
+		requester: newAPIRequester("fofa", 0),
+	}, nil
+}
+
+// Search 查询一页结果，FOFA 返回错误时返回 *PassiveAPIError
+func (c *FofaClient) Search(ctx context.Context, query string, fields []string, page, size int) (*FofaSearchResponse, error) {
+	params := netURL.Values{}
+	params.Set("qbase64", base64.URLEncoding.EncodeToString([]byte(query)))
+	params.Set("fields", strings.Join(fields, ","))
+	params.Set("page", strconv.Itoa(page))
+	params.Set("size", strconv.Itoa(size))
+
+	response := &FofaSearchResponse{}
+	if err := c.get(ctx, "/api/v1/search/all", params, response); err != nil {
+		return nil, err
+	}
+	if response.Error {
+		return nil, &PassiveAPIError{Source: "fofa", Message: response.ErrMsg}
+	}
+	return response, nil
+}
+
+// AccountInfo 查询账号信息和剩余额度，凭据错误时返回 *PassiveAPIError
+func (c *FofaClient) AccountInfo(ctx context.Context) (*FofaAccountInfo, error) {
+	info := &FofaAccountInfo{}
+	if err := c.get(ctx, "/api/v1/info/my", netURL.Values{}, info); err != nil {
+		return nil, err
+	}
+	if info.Error {
+		return nil, &PassiveAPIError{Source: "fofa", Message: info.ErrMsg}
+	}
+	return info, nil
+}
+
+// get 请求 FOFA 接口并解析返回的 JSON
+// FOFA 的业务错误（例如凭据错误）也可能使用非 2xx 的状态码，响应中有 errmsg 时作为 *PassiveAPIError 返回
+func (c *FofaClient) get(ctx context.Context, path string, params netURL.Values, result any) error {
+	params.Set("email", c.email)
+	params.Set("key", c.key)
+	content, err := c.requester.do(ctx, http.MethodGet, c.baseURL+path+"?"+params.Encode(), nil, nil)
+	if err != nil {
+		if message := apiErrorMessage(err, "errmsg"); message != "" {
+			return &PassiveAPIError{Source: "fofa", Message: message}
+		}
+		return err
+	}
+	if err := sonic.Unmarshal(content, result); err != nil {
+		return fmt.Errorf("error when parse fofa response: %w", err)
+	}
+	return nil
+}
+
+// FofaSource FOFA 数据源，使用 --fofa-query 指定的查询语句，按账号的剩余额度限制查询的页数
+type FofaSource struct {
+	client   *FofaClient
+	query    string
+	fields   []string
+	pageSize int
+	maxPages int
+	quota    *fofaQuota
+
+	hostIdx, ipIdx, portIdx, protocolIdx int
+}
+
+func newFofaSource(args *AppArgs, run *passiveRun, baseURL string) (PassiveSource, error) {
+	client, err := NewFofaClient(baseURL, args.FofaToken)
+	if err != nil {
+		return nil, err
+	}
+	fields := args.FofaFields
+	return &FofaSource{
+		client:      client,
+		query:       args.FofaQuery,
+		fields:      fields,
+		pageSize:    int(args.FofaPageSize),
+		maxPages:    int(args.FofaPages),
+		quota:       run.fofaQuota,
+		hostIdx:     slices.Index(fields, "host"),
+		ipIdx:       slices.Index(fields, "ip"),
+		portIdx:     slices.Index(fields, "port"),
+		protocolIdx: slices.Index(fields, "protocol"),
+	}, nil
+}
+
+func (s *FofaSource) Name() string {
+	return "fofa"
+}
+
+func (s *FofaSource) MaxPages() int {
+	return s.maxPages
+}
+
+func (s *FofaSource) Search(ctx context.Context, target string, page int) (*PassivePage, error) {
+	// 账号的剩余额度用完后不再查询
+	if !s.quota.take() {
+		return nil, errPassiveQuotaExhausted
+	}
+
+	query := buildFofaQuery(s.query, target)
+	logger.Debugf("FOFA query for %s page %d: %s", target, page, query)
+	response, err := s.client.Search(ctx, query, s.fields, page, s.pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PassivePage{Hosts: make([]PassiveHost, 0, len(response.Results)), More: len(response.Results) >= s.pageSize}
+	for _, row := range response.Results {
+		column := func(idx int) string {
+			if idx < 0 || idx >= len(row) {
+				return ""
+			}
+			return row[idx]
+		}
+		port, _ := strconv.Atoi(column(s.portIdx))
+		result.Hosts = append(result.Hosts, PassiveHost{
+			Host:    column(s.hostIdx),
+			Service: ServiceRecord{IP: column(s.ipIdx), Port: port, Protocol: column(s.protocolIdx)},
+		})
+	}
+	return result, nil
+}
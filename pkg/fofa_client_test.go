@@ -0,0 +1,286 @@
+package enumsubdomain
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// 测试中不初始化日志文件，重试时的日志直接丢弃
+	*logger = *zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+// withRetryDelay 缩短重试的等待时间，测试结束后恢复
+func withRetryDelay(t *testing.T, base, limit time.Duration) {
+	t.Helper()
+	oldBase, oldMax := apiRetryBaseDelay, apiRetryMaxDelay
+	apiRetryBaseDelay, apiRetryMaxDelay = base, limit
+	t.Cleanup(func() { apiRetryBaseDelay, apiRetryMaxDelay = oldBase, oldMax })
+}
+
+// fofaReply 模拟服务返回的一个响应
+type fofaReply struct {
+	status int
+	body   string
+}
+
+// fofaStub 按顺序返回 replies 的模拟 FOFA 服务，超出时重复最后一个响应，同时记录每个请求
+type fofaStub struct {
+	mutex    sync.Mutex
+	replies  []fofaReply
+	requests []*http.Request
+	times    []time.Time
+}
+
+func newFofaStub(t *testing.T, replies ...fofaReply) (*fofaStub, *FofaClient) {
+	t.Helper()
+	stub := &fofaStub{replies: replies}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.mutex.Lock()
+		idx := min(len(stub.requests), len(stub.replies)-1)
+		stub.requests = append(stub.requests, r)
+		stub.times = append(stub.times, time.Now())
+		reply := stub.replies[idx]
+		stub.mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(reply.status)
+		_, _ = w.Write([]byte(reply.body))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewFofaClient(server.URL, "user@example.com|secret")
+	if err != nil {
+		t.Fatalf("NewFofaClient: %v", err)
+	}
+	return stub, client
+}
+
+func (s *fofaStub) count() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.requests)
+}
+
+const fofaOKBody = `{"error": false, "size": 2, "page": 1, "results": [["www.example.com", "1.2.3.4", "443", "https"], ["mail.example.com", "5.6.7.8", "25", "smtp"]]}`
+
+func TestFofaClientSearchErrors(t *testing.T) {
+	withRetryDelay(t, time.Millisecond, 5*time.Millisecond)
+
+	tests := []struct {
+		name     string
+		replies  []fofaReply
+		message  string // 期望的 PassiveAPIError 信息，为空表示不是 PassiveAPIError
+		status   int    // 期望的 apiStatusError 状态码，为 0 表示不是 apiStatusError
+		requests int
+	}{
+		{
+			name:     "error in 200 response",
+			replies:  []fofaReply{{http.StatusOK, `{"error": true, "errmsg": "[820031] F点余额不足"}`}},
+			message:  "[820031] F点余额不足",
+			requests: 1,
+		},
+		{
+			name:     "errmsg in 401 response",
+			replies:  []fofaReply{{http.StatusUnauthorized, `{"error": true, "errmsg": "[-700] Account Invalid"}`}},
+			message:  "[-700] Account Invalid",
+			requests: 1,
+		},
+		{
+			name:     "400 without errmsg is not retried",
+			replies:  []fofaReply{{http.StatusBadRequest, `bad request`}},
+			status:   http.StatusBadRequest,
+			requests: 1,
+		},
+		{
+			name:     "5xx until retries exhausted",
+			replies:  []fofaReply{{http.StatusBadGateway, `<html>bad gateway</html>`}},
+			status:   http.StatusBadGateway,
+			requests: apiMaxRetries + 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub, client := newFofaStub(t, tt.replies...)
+			response, err := client.Search(context.Background(), `domain="example.com"`, DefaultFofaFields, 1, 100)
+			if err == nil {
+				t.Fatalf("expected error, got response %+v", response)
+			}
+
+			var apiError *PassiveAPIError
+			if tt.message != "" {
+				if !errors.As(err, &apiError) || apiError.Message != tt.message || apiError.Source != "fofa" {
+					t.Errorf("expected PassiveAPIError %q, got %v", tt.message, err)
+				}
+			} else if errors.As(err, &apiError) {
+				t.Errorf("unexpected PassiveAPIError: %v", err)
+			}
+
+			var statusError *apiStatusError
+			if tt.status != 0 && (!errors.As(err, &statusError) || statusError.statusCode != tt.status) {
+				t.Errorf("expected status %d, got %v", tt.status, err)
+			}
+			if stub.count() != tt.requests {
+				t.Errorf("expected %d requests, got %d", tt.requests, stub.count())
+			}
+		})
+	}
+}
+
+func TestFofaClientRetry(t *testing.T) {
+	const base = 20 * time.Millisecond
+	withRetryDelay(t, base, time.Second)
+
+	tests := []struct {
+		name    string
+		replies []fofaReply
+	}{
+		{
+			name:    "429 then success",
+			replies: []fofaReply{{http.StatusTooManyRequests, `{"error": true, "errmsg": "rate limited"}`}, {http.StatusTooManyRequests, ``}, {http.StatusOK, fofaOKBody}},
+		},
+		{
+			name:    "5xx then success",
+			replies: []fofaReply{{http.StatusServiceUnavailable, ``}, {http.StatusInternalServerError, ``}, {http.StatusOK, fofaOKBody}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub, client := newFofaStub(t, tt.replies...)
+			response, err := client.Search(context.Background(), `domain="example.com"`, DefaultFofaFields, 1, 100)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if len(response.Results) != 2 {
+				t.Errorf("expected 2 results, got %d", len(response.Results))
+			}
+			if stub.count() != len(tt.replies) {
+				t.Fatalf("expected %d requests, got %d", len(tt.replies), stub.count())
+			}
+
+			// 等待时间按指数退避：base、2 * base
+			for idx := 1; idx < len(stub.times); idx++ {
+				expected := base << (idx - 1)
+				if gap := stub.times[idx].Sub(stub.times[idx-1]); gap < expected {
+					t.Errorf("retry %d after %s, expected at least %s", idx, gap, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestFofaClientContextCancel(t *testing.T) {
+	// 等待时间足够长，只有取消才能让请求提前返回
+	withRetryDelay(t, time.Minute, time.Minute)
+
+	tests := []struct {
+		name     string
+		cancelAt int // 收到第几个请求后取消，为 0 表示请求前就已经取消
+		requests int
+	}{
+		{name: "canceled before request", cancelAt: 0, requests: 0},
+		{name: "canceled during backoff", cancelAt: 1, requests: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub, client := newFofaStub(t, fofaReply{http.StatusServiceUnavailable, ``})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelAt == 0 {
+				cancel()
+			} else {
+				go func() {
+					for stub.count() < tt.cancelAt {
+						time.Sleep(time.Millisecond)
+					}
+					cancel()
+				}()
+			}
+
+			done := make(chan error, 1)
+			go func() {
+				_, err := client.Search(ctx, `domain="example.com"`, DefaultFofaFields, 1, 100)
+				done <- err
+			}()
+			select {
+			case err := <-done:
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("expected context.Canceled, got %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Search did not return after cancel")
+			}
+			if stub.count() != tt.requests {
+				t.Errorf("expected %d requests, got %d", tt.requests, stub.count())
+			}
+		})
+	}
+}
+
+func TestFofaClientSearchRequest(t *testing.T) {
+	stub, client := newFofaStub(t, fofaReply{http.StatusOK, fofaOKBody})
+	query := `domain="example.com" && port="443"`
+	if _, err := client.Search(context.Background(), query, []string{"host", "ip"}, 3, 50); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	request := stub.requests[0]
+	if request.URL.Path != "/api/v1/search/all" {
+		t.Errorf("unexpected path %s", request.URL.Path)
+	}
+	params := request.URL.Query()
+	decoded, err := base64.URLEncoding.DecodeString(params.Get("qbase64"))
+	if err != nil || string(decoded) != query {
+		t.Errorf("qbase64 decoded to %q (%v), expected %q", decoded, err, query)
+	}
+	expected := map[string]string{"email": "user@example.com", "key": "secret", "fields": "host,ip", "page": "3", "size": "50"}
+	for name, value := range expected {
+		if params.Get(name) != value {
+			t.Errorf("param %s is %q, expected %q", name, params.Get(name), value)
+		}
+	}
+}
+
+func TestFofaRowUnmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected FofaRow
+	}{
+		{name: "strings", data: `["www.example.com", "1.2.3.4", "443", "https"]`, expected: FofaRow{"www.example.com", "1.2.3.4", "443", "https"}},
+		{name: "single field", data: `"www.example.com"`, expected: FofaRow{"www.example.com"}},
+		{name: "number and null", data: `["www.example.com", 8080, null, true]`, expected: FofaRow{"www.example.com", "8080", "", "true"}},
+		{name: "empty", data: `[]`, expected: FofaRow{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var row FofaRow
+			if err := sonic.Unmarshal([]byte(tt.data), &row); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if !slices.Equal(row, tt.expected) {
+				t.Errorf("got %q, expected %q", row, tt.expected)
+			}
+		})
+	}
+
+	// 只请求一个字段时 results 中的每条结果都是字符串
+	response := &FofaSearchResponse{}
+	if err := sonic.Unmarshal([]byte(`{"error": false, "results": ["a.example.com", "b.example.com"]}`), response); err != nil {
+		t.Fatalf("Unmarshal response: %v", err)
+	}
+	if len(response.Results) != 2 || !slices.Equal(response.Results[1], FofaRow{"b.example.com"}) {
+		t.Errorf("unexpected results %q", response.Results)
+	}
+}
@@ -15,6 +15,7 @@ type HTTPResult struct {
 	statusCode uint
 	bodyLength uint
 	error      string
+	probedAt   time.Time
 }
 
 var httpClient = &http.Client{
@@ -39,6 +40,7 @@ func FetchIndexTitle(domain string) *HTTPResult {
 	lastErr := ""
 	for _, url := range urls {
 		httpResult := makeRequest(url)
+		httpResult.probedAt = time.Now()
 		if httpResult.error == "" {
 			// 如果 error 字段是空的，说明请求成功了，直接返回 httpResult 即可
 			return httpResult
@@ -49,7 +51,7 @@ func FetchIndexTitle(domain string) *HTTPResult {
 	}
 
 	// 如果都请求失败了，则返回一个仅填充了 error 字段的 HTTPResult
-	return &HTTPResult{error: lastErr}
+	return &HTTPResult{error: lastErr, probedAt: time.Now()}
 }
 
 func makeRequest(url string) *HTTPResult {
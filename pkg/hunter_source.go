@@ -0,0 +1,118 @@
+package enumsubdomain
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/bytedance/sonic"
+	"net/http"
+	netURL "net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultHunterBaseURL 鹰图（Hunter）API 的默认地址
+const DefaultHunterBaseURL = "https://hunter.qianxin.com"
+
+// hunterPageSize Hunter 每页的结果数量，接口允许的最大值为 100
+const hunterPageSize = 100
+
+// HunterSearchResponse Hunter openApi/search 接口的返回结果
+//
+// 请求结果样例：
+//
+//	{
+//	    "code": 200,
+//	    "message": "success",
+//	    "data": {
+//	        "total": 2,
+//	        "arr": [
+//	            {"url": "https://c1.lightless.me", "ip": "43.129.25.182", "port": 443, "domain": "c1.lightless.me", "protocol": "https"},
+//	            {"url": "", "ip": "216.24.176.101", "port": 10022, "domain": "ss.lightless.me", "protocol": "ssh"}
+//	        ]
+//	    }
+//	}
+type HunterSearchResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Total int `json:"total"`
+		Arr   []struct {
+			URL      string `json:"url"`
+			IP       string `json:"ip"`
+			Port     int    `json:"port"`
+			Domain   string `json:"domain"`
+			Protocol string `json:"protocol"`
+		} `json:"arr"`
+	} `json:"data"`
+}
+
+// HunterSource 鹰图数据源，查询语句为 domain.suffix="target"
+type HunterSource struct {
+	baseURL   string
+	key       string
+	maxPages  int
+	requester *apiRequester
+}
+
+func newHunterSource(args *AppArgs, _ *passiveRun, baseURL string) (PassiveSource, error) {
+	if _, err := netURL.ParseRequestURI(baseURL); err != nil {
+		return nil, fmt.Errorf("hunter base url error: %w", err)
+	}
+	return &HunterSource{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		key:       args.Credentials["hunter"],
+		maxPages:  args.passiveMaxPages(),
+		requester: newAPIRequester("hunter", 0),
+	}, nil
+}
+
+func (s *HunterSource) Name() string {
+	return "hunter"
+}
+
+func (s *HunterSource) MaxPages() int {
+	return s.maxPages
+}
+
+func (s *HunterSource) Search(ctx context.Context, target string, page int) (*PassivePage, error) {
+	params := netURL.Values{}
+	params.Set("api-key", s.key)
+	params.Set("search", base64.URLEncoding.EncodeToString([]byte(fmt.Sprintf(`domain.suffix="%s"`, target))))
+	params.Set("page", strconv.Itoa(page))
+	params.Set("page_size", strconv.Itoa(hunterPageSize))
+	// is_web=3 表示同时查询 web 和非 web 资产
+	params.Set("is_web", "3")
+
+	content, err := s.requester.do(ctx, http.MethodGet, s.baseURL+"/openApi/search?"+params.Encode(), nil, nil)
+	if err != nil {
+		if message := apiErrorMessage(err, "message"); message != "" {
+			return nil, &PassiveAPIError{Source: "hunter", Message: message}
+		}
+		return nil, err
+	}
+	response := &HunterSearchResponse{}
+	if err := sonic.Unmarshal(content, response); err != nil {
+		return nil, fmt.Errorf("error when parse hunter response: %w", err)
+	}
+	// Hunter 的业务错误使用 200 状态码返回，错误码在 code 字段中
+	if response.Code != http.StatusOK {
+		return nil, &PassiveAPIError{Source: "hunter", Message: fmt.Sprintf("%d %s", response.Code, response.Message)}
+	}
+
+	result := &PassivePage{
+		Hosts: make([]PassiveHost, 0, len(response.Data.Arr)),
+		More:  page*hunterPageSize < response.Data.Total,
+	}
+	for _, item := range response.Data.Arr {
+		host := item.Domain
+		if host == "" {
+			host = item.URL
+		}
+		result.Hosts = append(result.Hosts, PassiveHost{
+			Host:    host,
+			Service: ServiceRecord{IP: item.IP, Port: item.Port, Protocol: item.Protocol},
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,162 @@
+package enumsubdomain
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// seedLine JSON Lines 格式的种子文件中的一行，兼容以下几种工具的输出：
+//
+//	amass:     {"name": "www.example.com", "domain": "example.com", "addresses": [{"ip": "1.2.3.4"}]}
+//	subfinder: {"host": "www.example.com", "input": "example.com", "source": "crtsh", "ip": "1.2.3.4"}
+//	本工具:     {"target": "example.com", "domain": "www.example.com", "services": [{"ip": "1.2.3.4"}]}
+type seedLine struct {
+	Name      string `json:"name"`
+	Host      string `json:"host"`
+	Domain    string `json:"domain"`
+	IP        string `json:"ip"`
+	Addresses []struct {
+		IP string `json:"ip"`
+	} `json:"addresses"`
+	Services []ServiceRecord `json:"services"`
+}
+
+// LoadSeedFile 读取其他工具输出的子域名，支持每行一个域名（或 URL）的列表、amass JSON、subfinder JSONL
+// 以及本工具之前输出的 csv、jsonl 和 json 结果，格式根据扩展名和文件内容判断
+func LoadSeedFile(filename string) ([]PassiveHost, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't read seed file %s: %w", filename, err)
+	}
+
+	// amass 的 -json 输出虽然扩展名是 .json，内容却是每行一个 JSON 对象
+	format := resultFormat(filename, content)
+	switch {
+	case bytes.HasPrefix(bytes.TrimSpace(content), []byte("{")):
+		return readSeedLines(filename, content)
+	case format != "txt":
+		records, err := parseResultRecords(content, format)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse seed file %s as %s: %w", filename, format, err)
+		}
+		hosts := make([]PassiveHost, 0, len(records))
+		for _, record := range records {
+			hosts = append(hosts, seedHosts(record.Domain, record.Services)...)
+		}
+		return hosts, nil
+	default:
+		hosts := make([]PassiveHost, 0)
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			// 兼容 host,ip 这类带有其他列的列表，只使用第一列
+			fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+			if len(fields) > 0 {
+				hosts = append(hosts, PassiveHost{Host: fields[0]})
+			}
+		}
+		return hosts, scanner.Err()
+	}
+}
+
+// readSeedLines 读取 JSON Lines 格式的种子文件，无法解析的行会被跳过
+func readSeedLines(filename string, content []byte) ([]PassiveHost, error) {
+	hosts := make([]PassiveHost, 0)
+	invalid := 0
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var seed seedLine
+		if err := json.Unmarshal(line, &seed); err != nil {
+			invalid++
+			continue
+		}
+
+		host := seed.Name
+		if host == "" {
+			host = seed.Host
+		}
+		if host == "" {
+			host = seed.Domain
+		}
+		services := seed.Services
+		if seed.IP != "" {
+			services = append(services, ServiceRecord{IP: seed.IP})
+		}
+		for _, address := range seed.Addresses {
+			services = append(services, ServiceRecord{IP: address.IP})
+		}
+		hosts = append(hosts, seedHosts(host, services)...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read seed file %s: %w", filename, err)
+	}
+	if invalid > 0 {
+		logger.Warnf("Skip %d invalid lines in seed file %s.", invalid, filename)
+	}
+	return hosts, nil
+}
+
+// seedHosts 把一个域名和它的服务转换为 PassiveHost，每个服务一条
+func seedHosts(host string, services []ServiceRecord) []PassiveHost {
+	if host == "" {
+		return nil
+	}
+	if len(services) == 0 {
+		return []PassiveHost{{Host: host}}
+	}
+	hosts := make([]PassiveHost, 0, len(services))
+	for _, service := range services {
+		hosts = append(hosts, PassiveHost{Host: host, Service: service})
+	}
+	return hosts
+}
+
+// ImportSource 从其他工具的输出中导入子域名，只保留目标范围内的域名，与被动数据源一样通过 DNS 验证
+type ImportSource struct {
+	hosts []PassiveHost
+}
+
+func newImportSource(args *AppArgs, _ *passiveRun, _ string) (PassiveSource, error) {
+	hosts := make([]PassiveHost, 0)
+	for _, filename := range args.ImportFiles {
+		seeds, err := LoadSeedFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		logger.Infof("Loaded %d seeds from %s.", len(seeds), filename)
+		hosts = append(hosts, seeds...)
+	}
+	return &ImportSource{hosts: hosts}, nil
+}
+
+func (s *ImportSource) Name() string {
+	return "import"
+}
+
+func (s *ImportSource) MaxPages() int {
+	return 1
+}
+
+func (s *ImportSource) Search(_ context.Context, target string, _ int) (*PassivePage, error) {
+	// 种子文件中可能同时有多个目标的域名，其他目标的域名不作为被动发现记录
+	result := &PassivePage{Hosts: make([]PassiveHost, 0)}
+	for _, host := range s.hosts {
+		if inScope(trimHost(host.Host), target) {
+			result.Hosts = append(result.Hosts, host)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,132 @@
+package enumsubdomain
+
+import (
+	"context"
+	"errors"
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+// Prometheus 指标，同一个进程中的所有扫描（例如 serve 模式下的多个任务）共用
+var (
+	metricDNSQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "enum_subdomain_dns_queries_total",
+		Help: "DNS queries sent, by nameserver.",
+	}, []string{"nameserver"})
+	metricDNSResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "enum_subdomain_dns_responses_total",
+		Help: "DNS responses received, by nameserver and rcode. Queries without response are counted with rcode \"ERROR\".",
+	}, []string{"nameserver", "rcode"})
+	metricDNSLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "enum_subdomain_dns_query_duration_seconds",
+		Help:    "Round-trip time of successful DNS queries, by nameserver.",
+		Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2},
+	}, []string{"nameserver"})
+	metricTasksGenerated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "enum_subdomain_tasks_generated_total",
+		Help: "Brute and dictionary tasks generated by the task builder.",
+	})
+	metricTasksResolved = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "enum_subdomain_tasks_resolved_total",
+		Help: "Domains resolved by the brute engine.",
+	})
+	metricQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "enum_subdomain_queue_depth",
+		Help: "Items waiting in the internal queues, sampled every second.",
+	}, []string{"queue"})
+	metricPassivePages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "enum_subdomain_passive_pages_total",
+		Help: "Result pages fetched from passive sources, by source (fofa, hunter, ...) and status (ok or error).",
+	}, []string{"source", "status"})
+	metricHTTPProbes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "enum_subdomain_http_probes_total",
+		Help: "HTTP title probes, by status (ok or error).",
+	}, []string{"status"})
+	metricResults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "enum_subdomain_results_total",
+		Help: "Unique subdomains found, by source technical.",
+	}, []string{"source"})
+	metricScansRunning = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "enum_subdomain_scans_running",
+		Help: "Scans currently running.",
+	})
+)
+
+// observeDNSQuery 记录一次 DNS 查询
+func observeDNSQuery(nameserver string, response *dns.Msg, rtt time.Duration, err error) {
+	metricDNSQueries.WithLabelValues(nameserver).Inc()
+	if err != nil || response == nil {
+		metricDNSResponses.WithLabelValues(nameserver, "ERROR").Inc()
+		return
+	}
+	metricDNSResponses.WithLabelValues(nameserver, dns.RcodeToString[response.Rcode]).Inc()
+	metricDNSLatency.WithLabelValues(nameserver).Observe(rtt.Seconds())
+}
+
+// observeStatus 按照是否出错记录 ok 或者 error
+func observeStatus(counter *prometheus.CounterVec, failed bool) {
+	if failed {
+		counter.WithLabelValues("error").Inc()
+	} else {
+		counter.WithLabelValues("ok").Inc()
+	}
+}
+
+// queueDepthSampler 定期采样队列的长度，多个扫描同时进行时各自只累加自己的变化量
+type queueDepthSampler struct {
+	queues map[string]func() int
+	last   map[string]int
+}
+
+func newQueueDepthSampler(queues map[string]func() int) *queueDepthSampler {
+	return &queueDepthSampler{queues: queues, last: make(map[string]int)}
+}
+
+func (s *queueDepthSampler) sample() {
+	for name, length := range s.queues {
+		current := length()
+		metricQueueDepth.WithLabelValues(name).Add(float64(current - s.last[name]))
+		s.last[name] = current
+	}
+}
+
+// run 每秒采样一次，stopChan 关闭后把自己累加的值清零
+func (s *queueDepthSampler) run(stopChan chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopChan:
+			for name, last := range s.last {
+				metricQueueDepth.WithLabelValues(name).Sub(float64(last))
+			}
+			return
+		case <-ticker.C:
+			s.sample()
+		}
+	}
+}
+
+// ServeMetrics 在 addr 上提供 /metrics，ctx 被取消后停止
+func ServeMetrics(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	logger.Infof("Metrics available at http://%s/metrics", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package enumsubdomain
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MonitorArgs monitor 命令的参数
+type MonitorArgs struct {
+	Interval    time.Duration // 两次扫描开始时间的间隔
+	Runs        uint          // 执行多少次后退出，0 表示一直执行
+	WebhookURL  string        // 每次扫描结束后把变化 POST 到该地址，为空表示不发送
+	WebhookType string        // webhook 消息格式：json、slack、dingtalk
+}
+
+// Monitor 按照固定的间隔重复扫描，把每次的结果与上一次对比，并通过 webhook 发送变化
+type Monitor struct {
+	appArgs      *AppArgs
+	monitorArgs  *MonitorArgs
+	notifier     *WebhookNotifier
+	baseline     []*ResultRecord
+	baselineName string
+}
+
+func NewMonitor(appArgs *AppArgs, monitorArgs *MonitorArgs) (*Monitor, error) {
+	if monitorArgs.Interval <= 0 {
+		return nil, fmt.Errorf("monitor interval must be positive")
+	}
+	if appArgs.ResumeFile != "" {
+		return nil, fmt.Errorf("resume can't be used in monitor mode")
+	}
+
+	// stdin 只能读取一次，先把目标读出来，普通的目标文件每次扫描时重新读取
+	if appArgs.TargetsFile == "-" {
+		targets, err := ReadTargetsFile(appArgs.TargetsFile)
+		if err != nil {
+			return nil, err
+		}
+		appArgs.Targets = append(appArgs.Targets, targets...)
+		appArgs.TargetsFile = ""
+	}
+
+	monitor := &Monitor{appArgs: appArgs, monitorArgs: monitorArgs}
+	if monitorArgs.WebhookURL != "" {
+		monitor.notifier = NewWebhookNotifier(monitorArgs.WebhookURL, monitorArgs.WebhookType)
+	}
+	return monitor, nil
+}
+
+// cloneArgs App 在检查参数时会修改 AppArgs，每次扫描都使用一份新的拷贝
+func (m *Monitor) cloneArgs() *AppArgs {
+	args := *m.appArgs
+	args.Targets = slices.Clone(m.appArgs.Targets)
+	args.Technicals = slices.Clone(m.appArgs.Technicals)
+	args.Outputs = slices.Clone(m.appArgs.Outputs)
+	args.Nameserver = slices.Clone(m.appArgs.Nameserver)
+	args.FofaFields = slices.Clone(m.appArgs.FofaFields)
+	args.ImportFiles = slices.Clone(m.appArgs.ImportFiles)
+	args.ExtractPaths = slices.Clone(m.appArgs.ExtractPaths)
+	args.PcapFiles = slices.Clone(m.appArgs.PcapFiles)
+	args.WildcardTargets = nil
+	return &args
+}
+
+// Run 开始循环扫描，ctx 被取消或者达到指定的次数后返回
+// 第一次扫描出错时直接返回错误，之后的错误只记录日志，下一次继续扫描
+func (m *Monitor) Run(ctx context.Context) error {
+	for round := uint(1); ; round++ {
+		startTime := time.Now()
+		err := m.runOnce(ctx, round)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			if round == 1 {
+				return err
+			}
+			logger.Errorf("Monitor run #%d failed, error: %+v", round, err)
+		}
+
+		if m.monitorArgs.Runs > 0 && round >= m.monitorArgs.Runs {
+			return nil
+		}
+
+		nextTime := startTime.Add(m.monitorArgs.Interval)
+		logger.Infof("Next monitor run at %s", nextTime.Format(time.DateTime))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(nextTime)):
+		}
+	}
+}
+
+func (m *Monitor) runOnce(ctx context.Context, round uint) error {
+	startTime := time.Now()
+	args := m.cloneArgs()
+	app := NewApp(args)
+
+	// --diff 指定的文件只用于第一次扫描，之后与上一次的结果对比；结果库中的扫描每次都重新读取
+	if round > 1 && !strings.HasPrefix(args.DiffAgainst, "run:") {
+		args.DiffAgainst = ""
+	}
+	if m.baseline != nil {
+		app.SetDiffBaseline(m.baselineName, m.baseline)
+	} else if args.DiffAgainst == "" {
+		args.DiffOutput = ""
+	}
+
+	logger.Infof("Start monitor run #%d", round)
+	results, err := app.RunContext(ctx)
+	if app.Summary() != nil {
+		logger.Info(app.Summary().String())
+	}
+	if err != nil {
+		return err
+	}
+
+	report := app.DiffReport()
+	if report == nil {
+		logger.Info("No previous results to compare with, results of this run are used as the baseline.")
+	} else if m.notifier != nil {
+		if err := m.notifier.Notify(ctx, args.Targets, report); err != nil {
+			logger.Errorf("Error when sending webhook, error: %+v", err)
+		} else if report.HasChanges() {
+			logger.Infof("Sent %d changes to webhook.", len(report.Entries()))
+		}
+	}
+
+	m.baseline = make([]*ResultRecord, 0, len(results))
+	for _, result := range results {
+		m.baseline = append(m.baseline, result.Record())
+	}
+	m.baselineName = fmt.Sprintf("monitor run #%d at %s", round, startTime.Format(time.DateTime))
+	return nil
+}
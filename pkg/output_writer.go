@@ -0,0 +1,274 @@
+package enumsubdomain
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// OutputFormats 支持的输出格式
+var OutputFormats = []string{"csv", "jsonl", "json", "txt"}
+
+// OutputWriter 扫描结果的输出目标，ResultEngine 会把去重后的每个结果依次写入所有的 OutputWriter
+type OutputWriter interface {
+	Write(result *SubdomainResult) error
+	Close() error
+}
+
+// OutputSpec 一个输出目标，命令行格式为 format:path，省略 format 时使用默认格式
+type OutputSpec struct {
+	Format string
+	Path   string
+}
+
+func (spec OutputSpec) String() string {
+	return fmt.Sprintf("%s:%s", spec.Format, spec.Path)
+}
+
+// ParseOutputSpec 解析 format:path 格式的输出参数
+// 只有冒号前面是已知的格式时才会切分，避免把 Windows 下的 C:\out.csv 当成格式
+func ParseOutputSpec(s, defaultFormat string) (OutputSpec, error) {
+	spec := OutputSpec{Format: strings.ToLower(defaultFormat), Path: strings.TrimSpace(s)}
+	if idx := strings.Index(spec.Path, ":"); idx > 0 {
+		format := strings.ToLower(spec.Path[:idx])
+		if slices.Contains(OutputFormats, format) {
+			spec.Format = format
+			spec.Path = spec.Path[idx+1:]
+		}
+	}
+
+	if spec.Format == "" {
+		spec.Format = "csv"
+	}
+	if !slices.Contains(OutputFormats, spec.Format) {
+		return spec, fmt.Errorf("output format error: %s, only %s allowed", spec.Format, strings.Join(OutputFormats, ", "))
+	}
+	if spec.Path == "" {
+		return spec, fmt.Errorf("output path can't be empty: %s", s)
+	}
+	return spec, nil
+}
+
+// NewOutputWriter 根据 spec 创建对应的 OutputWriter，appendMode 为 true 时在已有文件后追加，否则覆盖
+func NewOutputWriter(spec OutputSpec, appendMode bool) (OutputWriter, error) {
+	flag := os.O_RDWR | os.O_CREATE
+	if !appendMode {
+		flag |= os.O_TRUNC
+	}
+	fp, err := os.OpenFile(spec.Path, flag, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("can't open output file %s: %w", spec.Path, err)
+	}
+
+	var writer OutputWriter
+	switch spec.Format {
+	case "csv":
+		writer, err = newCSVOutputWriter(fp)
+	case "jsonl":
+		writer, err = newJSONLOutputWriter(fp)
+	case "json":
+		writer, err = newJSONOutputWriter(fp)
+	case "txt":
+		writer, err = newHostOutputWriter(fp)
+	default:
+		err = fmt.Errorf("unknown output format: %s", spec.Format)
+	}
+	if err != nil {
+		_ = fp.Close()
+		return nil, err
+	}
+	return writer, nil
+}
+
+// marshalRecord 把 ResultRecord 等结构序列化成单行 JSON，不转义标题中的 HTML 字符
+func marshalRecord(record any) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(record); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buffer.Bytes(), "\n"), nil
+}
+
+// seekEnd 把文件指针移到末尾，返回文件原有的长度
+func seekEnd(fp *os.File) (int64, error) {
+	return fp.Seek(0, io.SeekEnd)
+}
+
+// csvHeader CSV 输出的表头，新增的列只能加在最后，避免按位置读取的脚本出错
+var csvHeader = []string{"DOMAIN", "CNAME", "A", "STATUS_CODE", "TITLE", "LOCATION", "CONTENT_LENGTH", "HTTP_ERROR", "SERVICES", "TARGET"}
+
+// csvRow 把一个结果转换为一行 CSV，没有探测 HTTP 时状态码和长度为 0
+func csvRow(record *ResultRecord) []string {
+	probe := record.HTTP
+	if probe == nil {
+		probe = &HTTPRecord{}
+	}
+	services := make([]string, 0, len(record.Services))
+	for _, service := range record.Services {
+		services = append(services, service.String())
+	}
+	return []string{
+		record.Domain,
+		strings.Join(record.CNAME, ","),
+		strings.Join(record.A, ","),
+		strconv.Itoa(int(probe.StatusCode)),
+		probe.Title,
+		probe.Location,
+		strconv.Itoa(int(probe.BodyLength)),
+		probe.Error,
+		strings.Join(services, ","),
+		record.Target,
+	}
+}
+
+// csvOutputWriter 每个结果一行 CSV，CNAME、A 记录和服务用逗号拼接
+type csvOutputWriter struct {
+	fp     *os.File
+	writer *csv.Writer
+}
+
+func newCSVOutputWriter(fp *os.File) (*csvOutputWriter, error) {
+	size, err := seekEnd(fp)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &csvOutputWriter{fp: fp, writer: csv.NewWriter(fp)}
+	// 追加到已有文件时不再重复写表头
+	if size == 0 {
+		err = w.writer.Write(csvHeader)
+	}
+	return w, err
+}
+
+func (w *csvOutputWriter) Write(result *SubdomainResult) error {
+	if err := w.writer.Write(csvRow(result.Record())); err != nil {
+		return err
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *csvOutputWriter) Close() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		_ = w.fp.Close()
+		return err
+	}
+	return w.fp.Close()
+}
+
+// jsonlOutputWriter 每个结果一行 JSON
+type jsonlOutputWriter struct {
+	fp *os.File
+}
+
+func newJSONLOutputWriter(fp *os.File) (*jsonlOutputWriter, error) {
+	if _, err := seekEnd(fp); err != nil {
+		return nil, err
+	}
+	return &jsonlOutputWriter{fp: fp}, nil
+}
+
+func (w *jsonlOutputWriter) Write(result *SubdomainResult) error {
+	bs, err := marshalRecord(result.Record())
+	if err != nil {
+		return err
+	}
+	_, err = w.fp.Write(append(bs, '\n'))
+	return err
+}
+
+func (w *jsonlOutputWriter) Close() error {
+	return w.fp.Close()
+}
+
+// jsonOutputWriter 所有结果组成一个 JSON 数组，写入过程中流式输出，关闭时补上结尾的 ]
+type jsonOutputWriter struct {
+	fp    *os.File
+	count int
+}
+
+func newJSONOutputWriter(fp *os.File) (*jsonOutputWriter, error) {
+	size, err := seekEnd(fp)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &jsonOutputWriter{fp: fp}
+	if size == 0 {
+		_, err = fp.WriteString("[")
+		return w, err
+	}
+
+	// 追加到已有的数组时，去掉结尾的 ] 后继续写入
+	content, err := io.ReadAll(io.NewSectionReader(fp, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimRight(content, " \t\r\n")
+	if !bytes.HasPrefix(bytes.TrimSpace(trimmed), []byte("[")) || !bytes.HasSuffix(trimmed, []byte("]")) {
+		return nil, fmt.Errorf("can't append to %s, it is not a JSON array", fp.Name())
+	}
+	body := bytes.TrimRight(trimmed[:len(trimmed)-1], " \t\r\n")
+	if !bytes.HasSuffix(body, []byte("[")) {
+		w.count = 1
+	}
+	if err := fp.Truncate(int64(len(body))); err != nil {
+		return nil, err
+	}
+	_, err = fp.Seek(int64(len(body)), io.SeekStart)
+	return w, err
+}
+
+func (w *jsonOutputWriter) Write(result *SubdomainResult) error {
+	bs, err := marshalRecord(result.Record())
+	if err != nil {
+		return err
+	}
+	if w.count > 0 {
+		bs = append([]byte(",\n    "), bs...)
+	} else {
+		bs = append([]byte("\n    "), bs...)
+	}
+	w.count++
+	_, err = w.fp.Write(bs)
+	return err
+}
+
+func (w *jsonOutputWriter) Close() error {
+	if _, err := w.fp.WriteString("\n]\n"); err != nil {
+		_ = w.fp.Close()
+		return err
+	}
+	return w.fp.Close()
+}
+
+// hostOutputWriter 每行一个域名，方便交给其他工具继续处理
+type hostOutputWriter struct {
+	fp *os.File
+}
+
+func newHostOutputWriter(fp *os.File) (*hostOutputWriter, error) {
+	if _, err := seekEnd(fp); err != nil {
+		return nil, err
+	}
+	return &hostOutputWriter{fp: fp}, nil
+}
+
+func (w *hostOutputWriter) Write(result *SubdomainResult) error {
+	_, err := fmt.Fprintln(w.fp, result.dnsResult.domain)
+	return err
+}
+
+func (w *hostOutputWriter) Close() error {
+	return w.fp.Close()
+}
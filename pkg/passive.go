@@ -0,0 +1,113 @@
+package enumsubdomain
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 被动数据源中有记录、但是没有通过 DNS 验证的原因
+const (
+	PassiveUnresolved = "unresolved"   // 域名现在已经无法解析
+	PassiveIPOnly     = "ip-only"      // 只有 IP 没有域名
+	PassiveOutOfScope = "out-of-scope" // 不属于目标的域名，例如 cert= 查询到的其他域名
+)
+
+// PassiveFinding 只存在于被动数据源中的发现，过期的记录经常还指向存活的资产
+type PassiveFinding struct {
+	Target   string          `json:"target"`
+	Host     string          `json:"host"`   // 域名或者 IP
+	Source   string          `json:"source"` // 发现该记录的 technical
+	Reason   string          `json:"reason"`
+	Services []ServiceRecord `json:"services,omitempty"`
+	FoundAt  time.Time       `json:"found_at"`
+}
+
+// PassiveFindings 收集扫描过程中的 PassiveFinding，相同的记录会合并服务，所有方法都可以在 nil 上调用
+type PassiveFindings struct {
+	mutex    sync.Mutex
+	findings []*PassiveFinding
+	index    map[string]*PassiveFinding
+}
+
+func NewPassiveFindings() *PassiveFindings {
+	return &PassiveFindings{index: make(map[string]*PassiveFinding)}
+}
+
+// add 记录一个发现，同一个目标下原因和 host 都相同时只合并服务
+func (p *PassiveFindings) add(target, host, source, reason string, services []ServiceRecord) {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	key := strings.Join([]string{target, reason, host}, "|")
+	finding, ok := p.index[key]
+	if !ok {
+		finding = &PassiveFinding{Target: target, Host: host, Source: source, Reason: reason, FoundAt: time.Now()}
+		p.index[key] = finding
+		p.findings = append(p.findings, finding)
+	}
+	for _, service := range services {
+		if !slices.Contains(finding.Services, service) {
+			finding.Services = append(finding.Services, service)
+		}
+	}
+}
+
+// List 返回所有的发现，按发现的顺序排列
+func (p *PassiveFindings) List() []*PassiveFinding {
+	if p == nil {
+		return nil
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return slices.Clone(p.findings)
+}
+
+// String 按原因分组输出所有的发现，用于命令行
+func (p *PassiveFindings) String() string {
+	findings := p.List()
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Passive-only findings: %d", len(findings))
+	for _, reason := range []string{PassiveUnresolved, PassiveIPOnly, PassiveOutOfScope} {
+		for _, finding := range findings {
+			if finding.Reason != reason {
+				continue
+			}
+			fmt.Fprintf(&builder, "\n  [%s] %s", finding.Reason, finding.Host)
+			if len(finding.Services) > 0 {
+				services := make([]string, 0, len(finding.Services))
+				for _, service := range finding.Services {
+					services = append(services, service.String())
+				}
+				fmt.Fprintf(&builder, " %s", strings.Join(services, ","))
+			}
+		}
+	}
+	return builder.String()
+}
+
+// WriteFile 把所有的发现以 JSON Lines 格式写入文件
+func (p *PassiveFindings) WriteFile(filename string) error {
+	fp, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("can't open passive output file %s: %w", filename, err)
+	}
+	for _, finding := range p.List() {
+		bs, err := marshalRecord(finding)
+		if err != nil {
+			_ = fp.Close()
+			return err
+		}
+		if _, err := fp.Write(append(bs, '\n')); err != nil {
+			_ = fp.Close()
+			return err
+		}
+	}
+	return fp.Close()
+}
@@ -0,0 +1,219 @@
+package enumsubdomain
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// passiveTask 被动数据源的查询任务
+type passiveTask struct {
+	technical string
+	target    string
+}
+
+// passiveResult 从被动数据源获取到的待验证域名
+type passiveResult struct {
+	domain   string
+	target   string
+	source   string          // 数据源对应的 technical
+	services []ServiceRecord // 数据源中记录的该域名的服务
+}
+
+// inScope 判断域名是否为目标本身或者目标的子域名
+func inScope(domain, target string) bool {
+	return domain == target || strings.HasSuffix(domain, "."+target)
+}
+
+// PassiveEngine 查询所有指定的被动数据源，把目标范围内的域名交给 BruteEngine 验证
+// 每个数据源使用单独的 worker，互不阻塞
+type PassiveEngine struct {
+	ctx               context.Context
+	mainWG            *sync.WaitGroup
+	waitGroup         *sync.WaitGroup
+	passiveTaskChan   chan *passiveTask
+	passiveResultChan chan *passiveResult
+	appArgs           *AppArgs
+	run               *passiveRun
+	checkpointer      *Checkpointer
+	passive           *PassiveFindings
+}
+
+func NewPassiveEngine(ctx context.Context, appArgs *AppArgs, run *passiveRun, checkpointer *Checkpointer, passive *PassiveFindings, mainWG *sync.WaitGroup, passiveTaskChan chan *passiveTask, passiveResultChan chan *passiveResult) *PassiveEngine {
+	var wg sync.WaitGroup
+	return &PassiveEngine{
+		ctx:               ctx,
+		mainWG:            mainWG,
+		waitGroup:         &wg,
+		passiveTaskChan:   passiveTaskChan,
+		passiveResultChan: passiveResultChan,
+		appArgs:           appArgs,
+		run:               run,
+		checkpointer:      checkpointer,
+		passive:           passive,
+	}
+}
+
+func (engine *PassiveEngine) Run() {
+	defer func() {
+		engine.mainWG.Done()
+		close(engine.passiveResultChan)
+	}()
+
+	// 为每个指定的数据源启动一个 worker
+	taskChans := make(map[string]chan string)
+	for _, tech := range engine.appArgs.Technicals {
+		info := passiveSourceByTechnical(tech)
+		if info == nil || taskChans[tech] != nil {
+			continue
+		}
+		source, err := info.newSource(engine.appArgs, engine.run, engine.appArgs.sourceBaseURL(info))
+		if err != nil {
+			logger.Errorf("PassiveEngine error when create %s source: %+v", info.name, err)
+			continue
+		}
+		taskChan := make(chan string, 1)
+		taskChans[tech] = taskChan
+		engine.waitGroup.Add(1)
+		go engine.worker(tech, source, taskChan)
+	}
+
+	// 把任务分发给对应数据源的 worker，被中断后只消费掉剩下的任务
+	for task := range engine.passiveTaskChan {
+		taskChan, ok := taskChans[task.technical]
+		if !ok {
+			logger.Warnf("No passive source for technical %s, skip %s.", task.technical, task.target)
+			continue
+		}
+		select {
+		case taskChan <- task.target:
+		case <-engine.ctx.Done():
+		}
+	}
+	for _, taskChan := range taskChans {
+		close(taskChan)
+	}
+
+	engine.waitGroup.Wait()
+}
+
+func (engine *PassiveEngine) worker(tech string, source PassiveSource, taskChan chan string) {
+	defer engine.waitGroup.Done()
+
+	name := source.Name()
+	logger.Debugf("PassiveEngine %s start.", name)
+	for target := range taskChan {
+		logger.Debugf("Received %s task: %+v", name, target)
+		domains, services, complete := engine.query(source, target)
+
+		// 获取完成，通过 channel 发送给 brute_engine 验证
+		engine.checkpointer.passiveQueued(tech, target, len(domains), complete)
+		for _, domain := range domains {
+			logger.Debugf("Put %s to channel", domain)
+			select {
+			case engine.passiveResultChan <- &passiveResult{domain: domain, target: target, source: tech, services: services[domain]}:
+			case <-engine.ctx.Done():
+				return
+			}
+		}
+		logger.Infof("Found %d domain from %s for %s, start verify...", len(domains), name, target)
+	}
+	logger.Debugf("PassiveEngine %s end.", name)
+}
+
+// query 按页查询一个目标，返回目标范围内的域名及其服务，complete 表示所有的页面都已经获取成功
+// 只有 IP 的记录和目标以外的域名无法验证，作为被动发现单独记录
+func (engine *PassiveEngine) query(source PassiveSource, target string) ([]string, map[string][]ServiceRecord, bool) {
+	name := source.Name()
+	tech := passiveTechnical(name)
+	pageCounter := metricPassivePages.MustCurryWith(prometheus.Labels{"source": name})
+
+	// 按查询到的顺序保存域名，以及每个域名对应的服务
+	var domains []string
+	seen := make(map[string]bool)
+	services := make(map[string][]ServiceRecord)
+	complete := true
+	for p := 1; p <= source.MaxPages(); p++ {
+		if engine.ctx.Err() != nil {
+			logger.Infof("%s query interrupted at page %d.", name, p)
+			complete = false
+			break
+		}
+
+		logger.Debugf("Start fetch %s page %d for %s", name, p, target)
+		page, err := source.Search(engine.ctx, target, p)
+		if engine.ctx.Err() != nil {
+			logger.Infof("%s query interrupted at page %d.", name, p)
+			complete = false
+			break
+		}
+		if errors.Is(err, errPassiveQuotaExhausted) {
+			logger.Warnf("%s quota exhausted, stop querying %s at page %d.", name, target, p)
+			complete = false
+			break
+		}
+		observeStatus(pageCounter, err != nil)
+
+		// 数据源返回的错误（凭据错误、额度不足、查询语句错误等）重试也不会成功，停止查询该目标
+		var apiError *PassiveAPIError
+		if errors.As(err, &apiError) {
+			logger.Errorf("%s query for %s failed at page %d: %s", name, target, p, apiError.Message)
+			complete = false
+			break
+		} else if err != nil {
+			logger.Warnf("error when fetch %s page %d for %s, error: %+v, skip this page", name, p, target, err)
+			complete = false
+			continue
+		}
+
+		for _, host := range page.Hosts {
+			domain := trimHost(host.Host)
+			var serviceList []ServiceRecord
+			if host.Service != (ServiceRecord{}) {
+				serviceList = []ServiceRecord{host.Service}
+			}
+			if domain == "" {
+				domain = host.Service.IP
+			}
+			if domain == "" {
+				continue
+			}
+			if net.ParseIP(domain) != nil {
+				engine.passive.add(target, domain, tech, PassiveIPOnly, serviceList)
+				continue
+			}
+			if !inScope(domain, target) {
+				engine.passive.add(target, domain, tech, PassiveOutOfScope, serviceList)
+				continue
+			}
+
+			if !seen[domain] {
+				seen[domain] = true
+				domains = append(domains, domain)
+			}
+			if serviceList != nil && !slices.Contains(services[domain], host.Service) {
+				services[domain] = append(services[domain], host.Service)
+			}
+		}
+
+		// 没有更多结果时不需要再请求下一页
+		if !page.More || len(page.Hosts) == 0 {
+			break
+		}
+	}
+	return domains, services, complete
+}
+
+// passiveTechnical 根据数据源名称查找对应的 technical
+func passiveTechnical(name string) string {
+	for _, info := range passiveSourceRegistry {
+		if info.name == name {
+			return info.technical
+		}
+	}
+	return ""
+}
@@ -0,0 +1,344 @@
+package enumsubdomain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bytedance/sonic"
+	"io"
+	"net/http"
+	netURL "net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PassiveSource 被动数据源，按页查询目标的子域名
+type PassiveSource interface {
+	// Name 数据源名称，同时也是凭据的名称，例如 fofa
+	Name() string
+	// MaxPages 每个目标最多查询的页数
+	MaxPages() int
+	// Search 查询一页结果，没有更多结果时 PassivePage.More 为 false
+	// 数据源返回的错误（凭据错误、额度不足等）使用 *PassiveAPIError，重试也不会成功
+	Search(ctx context.Context, target string, page int) (*PassivePage, error)
+}
+
+// PassivePage 被动数据源的一页结果
+type PassivePage struct {
+	Hosts []PassiveHost
+	More  bool
+}
+
+// PassiveHost 被动数据源中的一条记录，Host 可以是域名、host:port、URL 或者 IP
+type PassiveHost struct {
+	Host    string
+	Service ServiceRecord
+}
+
+// PassiveAPIError 被动数据源接口返回的错误，例如凭据错误、额度不足、查询语句错误，重试也不会成功
+type PassiveAPIError struct {
+	Source  string
+	Message string
+}
+
+func (e *PassiveAPIError) Error() string {
+	return fmt.Sprintf("%s api error: %s", e.Source, e.Message)
+}
+
+// errPassiveQuotaExhausted 账号的剩余额度已经用完
+var errPassiveQuotaExhausted = errors.New("quota exhausted")
+
+// passiveRun 一次扫描中被动数据源共享的状态，由 App 在每次扫描开始时重新创建，不保存在 AppArgs 中
+type passiveRun struct {
+	fofaQuota       *fofaQuota       // 根据 FOFA 账号剩余额度计算的页数额度，为 nil 时不限制
+	archivedURLs    *ArchivedURLs    // 设置了 WaybackOutput 时收集网页存档中的历史 URL
+	dnsObservations *DNSObservations // 设置了 PcapOutput 时收集抓包中的 DNS 解析结果
+}
+
+// passiveSourceInfo 一个被动数据源的注册信息
+type passiveSourceInfo struct {
+	name      string
+	technical string
+	baseURL   string                                                                      // 默认的 API 地址
+	keyFormat string                                                                      // 凭据的格式，用于错误提示，为空表示不需要凭据
+	newSource func(args *AppArgs, run *passiveRun, baseURL string) (PassiveSource, error) // baseURL 为 sourceBaseURL 返回的 API 地址
+}
+
+// passiveSourceRegistry 所有的被动数据源，technical 不能重复
+var passiveSourceRegistry = []passiveSourceInfo{
+	{name: "fofa", technical: "F", baseURL: DefaultFofaBaseURL, keyFormat: "email|key", newSource: newFofaSource},
+	{name: "hunter", technical: "H", baseURL: DefaultHunterBaseURL, keyFormat: "api key", newSource: newHunterSource},
+	{name: "quake", technical: "Q", baseURL: DefaultQuakeBaseURL, keyFormat: "api token", newSource: newQuakeSource},
+	{name: "zoomeye", technical: "Z", baseURL: DefaultZoomEyeBaseURL, keyFormat: "api key", newSource: newZoomEyeSource},
+	{name: "virustotal", technical: "V", baseURL: DefaultVirusTotalBaseURL, keyFormat: "api key", newSource: newVirusTotalSource},
+	{name: "securitytrails", technical: "T", baseURL: DefaultSecurityTrailsBaseURL, keyFormat: "api key", newSource: newSecurityTrailsSource},
+	{name: "shodan", technical: "S", baseURL: DefaultShodanBaseURL, keyFormat: "api key", newSource: newShodanSource},
+	{name: "wayback", technical: "W", baseURL: DefaultWaybackBaseURL, newSource: newWaybackSource},
+	// 本地的种子文件和源码，没有 API 地址
+	{name: "import", technical: "I", newSource: newImportSource},
+	{name: "extract", technical: "E", newSource: newExtractSource},
+	{name: "pcap", technical: "P", newSource: newPcapSource},
+}
+
+// PassiveSources 所有被动数据源的名称，凭据的名称与数据源相同
+var PassiveSources = passiveSourceNames(false)
+
+// remoteSources 有 API 地址的被动数据源，可以通过 --source-base-url 修改地址
+var remoteSources = passiveSourceNames(true)
+
+func passiveSourceNames(remoteOnly bool) []string {
+	names := make([]string, 0, len(passiveSourceRegistry))
+	for _, info := range passiveSourceRegistry {
+		if !remoteOnly || info.baseURL != "" {
+			names = append(names, info.name)
+		}
+	}
+	return names
+}
+
+// passiveSourceByTechnical 根据 technical 查找被动数据源，不是被动数据源时返回 nil
+func passiveSourceByTechnical(technical string) *passiveSourceInfo {
+	for idx := range passiveSourceRegistry {
+		if passiveSourceRegistry[idx].technical == technical {
+			return &passiveSourceRegistry[idx]
+		}
+	}
+	return nil
+}
+
+// isPassiveTechnical 判断 technical 是否为被动数据源
+func isPassiveTechnical(technical string) bool {
+	return passiveSourceByTechnical(technical) != nil
+}
+
+// hasPassiveTechnical 是否指定了任意一个被动数据源
+func (a *AppArgs) hasPassiveTechnical() bool {
+	return slices.ContainsFunc(a.Technicals, isPassiveTechnical)
+}
+
+// sourceBaseURL 返回数据源的 API 地址，优先使用 SourceBaseURLs 中指定的地址
+func (a *AppArgs) sourceBaseURL(info *passiveSourceInfo) string {
+	if baseURL := a.SourceBaseURLs[info.name]; baseURL != "" {
+		return baseURL
+	}
+	if info.name == "fofa" && a.FofaBaseURL != "" {
+		return a.FofaBaseURL
+	}
+	return info.baseURL
+}
+
+// parseSourceBaseURLs 解析 name=url 格式的数据源 API 地址
+func parseSourceBaseURLs(values []string) (map[string]string, error) {
+	baseURLs := make(map[string]string, len(values))
+	for _, value := range values {
+		name, baseURL, found := strings.Cut(value, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !found || !slices.Contains(remoteSources, name) {
+			return nil, fmt.Errorf("source base url format error: %s, should be name=url, available names: %s", value, strings.Join(remoteSources, ", "))
+		}
+		baseURL = strings.TrimSpace(baseURL)
+		if _, err := netURL.ParseRequestURI(baseURL); err != nil {
+			return nil, fmt.Errorf("%s base url error: %w", name, err)
+		}
+		baseURLs[name] = baseURL
+	}
+	return baseURLs, nil
+}
+
+// passiveMaxPages 除 FOFA 以外的数据源每个目标最多查询的页数
+func (a *AppArgs) passiveMaxPages() int {
+	if a.PassivePages == 0 {
+		return DefaultPassivePages
+	}
+	return int(a.PassivePages)
+}
+
+// DefaultPassivePages 除 FOFA 以外的数据源每个目标默认查询的页数
+const DefaultPassivePages = 10
+
+// apiMaxRetries 被动数据源请求失败时最多重试 3 次
+const apiMaxRetries = 3
+
+// 重试的等待时间从 2s 开始翻倍，最长 30s，测试时会缩短
+var (
+	apiRetryBaseDelay = 2 * time.Second
+	apiRetryMaxDelay  = 30 * time.Second
+)
+
+// apiStatusError 接口返回了非 2xx 的状态码，body 为响应内容，用于解析数据源自己的错误信息
+type apiStatusError struct {
+	statusCode int
+	retryAfter time.Duration // 响应中 Retry-After 指定的等待时间，没有时为 0
+	body       []byte
+}
+
+func (e *apiStatusError) Error() string {
+	return fmt.Sprintf("returned status %d", e.statusCode)
+}
+
+// apiRequester 被动数据源共用的 HTTP 请求，网络错误、429 和 5xx 按指数退避重试
+// interval 大于 0 时两次请求之间至少间隔 interval，用于遵守数据源的频率限制
+type apiRequester struct {
+	source     string
+	httpClient *http.Client
+	interval   time.Duration
+
+	mutex sync.Mutex
+	next  time.Time // 下一次允许发送请求的时间
+}
+
+func newAPIRequester(source string, interval time.Duration) *apiRequester {
+	return &apiRequester{source: source, httpClient: &http.Client{Timeout: 30 * time.Second}, interval: interval}
+}
+
+// wait 等待到允许发送下一次请求的时间
+func (r *apiRequester) wait(ctx context.Context) error {
+	if r.interval <= 0 {
+		return nil
+	}
+	r.mutex.Lock()
+	now := time.Now()
+	start := now
+	if r.next.After(now) {
+		start = r.next
+	}
+	r.next = start.Add(r.interval)
+	r.mutex.Unlock()
+
+	if delay := start.Sub(now); delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// do 发送请求并返回 2xx 的响应内容，其他状态码返回 *apiStatusError，错误中的 URL 会隐藏凭据
+func (r *apiRequester) do(ctx context.Context, method, url string, header http.Header, body []byte) ([]byte, error) {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err := r.wait(ctx); err != nil {
+			return nil, err
+		}
+		var content []byte
+		if content, err = r.fetch(ctx, method, url, header, body); err == nil {
+			return content, nil
+		}
+
+		// 被取消或者不需要重试的错误直接返回
+		var statusError *apiStatusError
+		isStatusError := errors.As(err, &statusError)
+		if ctx.Err() != nil || attempt >= apiMaxRetries || (isStatusError && !retryableStatus(statusError.statusCode)) {
+			return nil, redactError(err)
+		}
+
+		delay := apiRetryBaseDelay << attempt
+		if isStatusError && statusError.retryAfter > delay {
+			delay = statusError.retryAfter
+		}
+		if delay > apiRetryMaxDelay {
+			delay = apiRetryMaxDelay
+		}
+		logger.Warnf("error when request %s: %+v, retry after %s", r.source, redactError(err), delay)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// fetch 发送一次请求
+func (r *apiRequester) fetch(ctx context.Context, method, url string, header http.Header, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	response, err := r.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return content, nil
+	}
+
+	statusError := &apiStatusError{statusCode: response.StatusCode, body: content}
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		statusError.retryAfter = time.Duration(seconds) * time.Second
+	}
+	return nil, statusError
+}
+
+// retryableStatus 限流和服务端错误可以重试
+func retryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// apiErrorMessage 从错误响应中取出数据源的错误信息，依次尝试 fields 中的字段，都没有时返回空字符串
+// 字段是对象时使用其中的 message，例如 {"error": {"code": "...", "message": "..."}}
+func apiErrorMessage(err error, fields ...string) string {
+	var statusError *apiStatusError
+	if !errors.As(err, &statusError) {
+		return ""
+	}
+	var body map[string]any
+	if sonic.Unmarshal(statusError.body, &body) != nil {
+		return ""
+	}
+	for _, field := range fields {
+		switch value := body[field].(type) {
+		case string:
+			if value != "" {
+				return value
+			}
+		case map[string]any:
+			if message, ok := value["message"].(string); ok && message != "" {
+				return message
+			}
+		}
+	}
+	return ""
+}
+
+// trimHost 去掉 URL 的协议、路径和端口，返回小写的主机名，IPv6 地址会去掉方括号
+func trimHost(raw string) string {
+	host := strings.TrimSpace(raw)
+	if idx := strings.Index(host, "://"); idx >= 0 {
+		host = host[idx+3:]
+	}
+	if idx := strings.IndexAny(host, "/?#"); idx >= 0 {
+		host = host[:idx]
+	}
+	if idx := strings.LastIndex(host, "@"); idx >= 0 {
+		host = host[idx+1:]
+	}
+	if strings.HasPrefix(host, "[") {
+		if idx := strings.Index(host, "]"); idx > 0 {
+			return strings.ToLower(host[1:idx])
+		}
+	}
+	if strings.Count(host, ":") == 1 {
+		host = host[:strings.Index(host, ":")]
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
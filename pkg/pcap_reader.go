@@ -0,0 +1,296 @@
+package enumsubdomain
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// 抓包文件中用到的链路层类型，参考 https://www.tcpdump.org/linktypes.html
+const (
+	linkTypeNull     = 0
+	linkTypeEthernet = 1
+	linkTypeRaw      = 101
+	linkTypeLoop     = 108
+	linkTypeLinuxSLL = 113
+	linkTypeIPv4     = 228
+	linkTypeIPv6     = 229
+	linkTypeSLL2     = 276
+)
+
+// pcapng 的 block 类型
+const (
+	pcapngSectionHeader  = 0x0A0D0D0A
+	pcapngInterface      = 0x00000001
+	pcapngPacket         = 0x00000002 // 已经废弃的 Packet Block
+	pcapngSimplePacket   = 0x00000003
+	pcapngEnhancedPacket = 0x00000006
+	pcapngByteOrderMagic = 0x1A2B3C4D
+)
+
+// maxCaptureRecordBytes 单个数据包或 block 的最大长度，超过时认为文件已经损坏
+const maxCaptureRecordBytes = 64 * 1024 * 1024
+
+// readCapture 读取 pcap 或 pcapng 格式的抓包文件，对每个数据包调用 handle
+// 不依赖 libpcap，只解析文件格式，不关心时间戳
+func readCapture(r io.Reader, handle func(linkType uint32, data []byte)) error {
+	reader := bufio.NewReaderSize(r, 1024*1024)
+	magic, err := reader.Peek(4)
+	if err != nil {
+		return fmt.Errorf("can't read capture header: %w", err)
+	}
+
+	switch binary.LittleEndian.Uint32(magic) {
+	case 0xa1b2c3d4, 0xa1b23c4d:
+		return readPcap(reader, binary.LittleEndian, handle)
+	case 0xd4c3b2a1, 0x4d3cb2a1:
+		return readPcap(reader, binary.BigEndian, handle)
+	case pcapngSectionHeader:
+		return readPcapng(reader, handle)
+	default:
+		return fmt.Errorf("unknown capture format, magic: %x", magic)
+	}
+}
+
+// readPcap 读取 pcap 格式：24 字节的文件头，之后每个数据包有 16 字节的记录头
+func readPcap(reader io.Reader, order binary.ByteOrder, handle func(linkType uint32, data []byte)) error {
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return fmt.Errorf("can't read pcap header: %w", err)
+	}
+	// 高 4 位可能是 FCS 信息，链路层类型只使用低 16 位
+	linkType := order.Uint32(header[20:24]) & 0xffff
+
+	record := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(reader, record); err != nil {
+			// 抓包被中断时最后一个记录头可能不完整
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return fmt.Errorf("can't read pcap record: %w", err)
+		}
+		capLen := order.Uint32(record[8:12])
+		if capLen > maxCaptureRecordBytes {
+			return fmt.Errorf("pcap record too large: %d bytes", capLen)
+		}
+		data := make([]byte, capLen)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			// 抓包被中断时最后一个数据包可能不完整
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return fmt.Errorf("can't read pcap packet: %w", err)
+		}
+		handle(linkType, data)
+	}
+}
+
+// readPcapng 读取 pcapng 格式，每个 section 有自己的字节序和接口列表
+func readPcapng(reader io.Reader, handle func(linkType uint32, data []byte)) error {
+	var order binary.ByteOrder = binary.LittleEndian
+	var interfaces []uint32 // 每个接口的链路层类型
+
+	head := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(reader, head); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return fmt.Errorf("can't read pcapng block: %w", err)
+		}
+
+		blockType := order.Uint32(head[0:4])
+		if blockType == pcapngSectionHeader {
+			// 新的 section，根据 byte-order magic 确定之后的字节序
+			bom := make([]byte, 4)
+			if _, err := io.ReadFull(reader, bom); err != nil {
+				return fmt.Errorf("can't read pcapng section header: %w", err)
+			}
+			switch {
+			case binary.LittleEndian.Uint32(bom) == pcapngByteOrderMagic:
+				order = binary.LittleEndian
+			case binary.BigEndian.Uint32(bom) == pcapngByteOrderMagic:
+				order = binary.BigEndian
+			default:
+				return fmt.Errorf("pcapng byte-order magic error: %x", bom)
+			}
+			interfaces = nil
+			length := order.Uint32(head[4:8])
+			if length < 28 || length > maxCaptureRecordBytes {
+				return fmt.Errorf("pcapng section header length error: %d", length)
+			}
+			if _, err := io.CopyN(io.Discard, reader, int64(length-12)); err != nil {
+				return fmt.Errorf("can't read pcapng section header: %w", err)
+			}
+			continue
+		}
+
+		// body 包括 block 末尾重复的 4 字节长度
+		length := order.Uint32(head[4:8])
+		if length < 12 || length%4 != 0 || length > maxCaptureRecordBytes {
+			return fmt.Errorf("pcapng block length error: %d", length)
+		}
+		body := make([]byte, length-8)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return fmt.Errorf("can't read pcapng block: %w", err)
+		}
+		body = body[:len(body)-4]
+
+		linkType := func(id uint32) (uint32, bool) {
+			if int(id) >= len(interfaces) {
+				return 0, false
+			}
+			return interfaces[id], true
+		}
+		switch blockType {
+		case pcapngInterface:
+			if len(body) >= 2 {
+				interfaces = append(interfaces, uint32(order.Uint16(body[0:2])))
+			}
+		case pcapngEnhancedPacket:
+			if len(body) < 20 {
+				continue
+			}
+			capLen := order.Uint32(body[12:16])
+			if lt, ok := linkType(order.Uint32(body[0:4])); ok && int(capLen) <= len(body)-20 {
+				handle(lt, body[20:20+capLen])
+			}
+		case pcapngPacket:
+			if len(body) < 20 {
+				continue
+			}
+			capLen := order.Uint32(body[12:16])
+			if lt, ok := linkType(uint32(order.Uint16(body[0:2]))); ok && int(capLen) <= len(body)-20 {
+				handle(lt, body[20:20+capLen])
+			}
+		case pcapngSimplePacket:
+			// Simple Packet Block 没有记录抓取长度，使用原始长度和 block 长度中较小的一个
+			if len(body) < 4 {
+				continue
+			}
+			capLen := min(int(order.Uint32(body[0:4])), len(body)-4)
+			if lt, ok := linkType(0); ok {
+				handle(lt, body[4:4+capLen])
+			}
+		}
+	}
+}
+
+// transportPayload 从链路层数据中解析出 UDP 或 TCP 的端口和负载，不支持的协议和 IP 分片返回 false
+func transportPayload(linkType uint32, data []byte) (srcPort, dstPort uint16, payload []byte, tcp bool, ok bool) {
+	network, ok := networkLayer(linkType, data)
+	if !ok {
+		return 0, 0, nil, false, false
+	}
+	protocol, segment, ok := ipPayload(network)
+	if !ok {
+		return 0, 0, nil, false, false
+	}
+
+	switch protocol {
+	case 17: // UDP
+		if len(segment) < 8 {
+			return 0, 0, nil, false, false
+		}
+		return binary.BigEndian.Uint16(segment[0:2]), binary.BigEndian.Uint16(segment[2:4]), segment[8:], false, true
+	case 6: // TCP
+		if len(segment) < 20 {
+			return 0, 0, nil, false, false
+		}
+		offset := int(segment[12]>>4) * 4
+		if offset < 20 || offset > len(segment) {
+			return 0, 0, nil, false, false
+		}
+		return binary.BigEndian.Uint16(segment[0:2]), binary.BigEndian.Uint16(segment[2:4]), segment[offset:], true, true
+	}
+	return 0, 0, nil, false, false
+}
+
+// networkLayer 去掉链路层的头部，返回 IP 数据包
+func networkLayer(linkType uint32, data []byte) ([]byte, bool) {
+	switch linkType {
+	case linkTypeEthernet:
+		if len(data) < 14 {
+			return nil, false
+		}
+		etherType, offset := binary.BigEndian.Uint16(data[12:14]), 14
+		// 跳过 VLAN 标签
+		for (etherType == 0x8100 || etherType == 0x88a8) && len(data) >= offset+4 {
+			etherType, offset = binary.BigEndian.Uint16(data[offset+2:offset+4]), offset+4
+		}
+		if etherType != 0x0800 && etherType != 0x86dd {
+			return nil, false
+		}
+		return data[offset:], true
+	case linkTypeNull, linkTypeLoop:
+		if len(data) < 4 {
+			return nil, false
+		}
+		return data[4:], true
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6:
+		return data, true
+	case linkTypeLinuxSLL:
+		if len(data) < 16 {
+			return nil, false
+		}
+		return data[16:], true
+	case linkTypeSLL2:
+		if len(data) < 20 {
+			return nil, false
+		}
+		return data[20:], true
+	}
+	return nil, false
+}
+
+// ipPayload 解析 IPv4 或 IPv6 数据包，返回上层协议号和负载，IP 分片无法单独解析，直接忽略
+func ipPayload(packet []byte) (uint8, []byte, bool) {
+	if len(packet) < 1 {
+		return 0, nil, false
+	}
+	switch packet[0] >> 4 {
+	case 4:
+		if len(packet) < 20 {
+			return 0, nil, false
+		}
+		headerLen := int(packet[0]&0x0f) * 4
+		totalLen := int(binary.BigEndian.Uint16(packet[2:4]))
+		// 有 MF 标志或者偏移不为 0 的都是分片
+		if binary.BigEndian.Uint16(packet[6:8])&0x3fff != 0 || headerLen < 20 || headerLen > len(packet) {
+			return 0, nil, false
+		}
+		end := len(packet)
+		if totalLen >= headerLen && totalLen < end {
+			end = totalLen
+		}
+		return packet[9], packet[headerLen:end], true
+	case 6:
+		if len(packet) < 40 {
+			return 0, nil, false
+		}
+		next := packet[6]
+		end := len(packet)
+		if payloadLen := int(binary.BigEndian.Uint16(packet[4:6])); payloadLen > 0 && 40+payloadLen < end {
+			end = 40 + payloadLen
+		}
+		offset := 40
+		// 跳过逐跳选项、路由和目的选项扩展头
+		for next == 0 || next == 43 || next == 60 {
+			if offset+2 > end {
+				return 0, nil, false
+			}
+			next, offset = packet[offset], offset+(int(packet[offset+1])+1)*8
+		}
+		if next == 44 || offset > end {
+			return 0, nil, false
+		}
+		return next, packet[offset:end], true
+	}
+	return 0, nil, false
+}
@@ -0,0 +1,288 @@
+package enumsubdomain
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/miekg/dns"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// dnsPorts 抓包中按 DNS 协议解析的端口，包括 mDNS
+var dnsPorts = []uint16{53, 5353}
+
+// DNSObservation 抓包中观察到的一个域名，以及 DNS 响应中该域名的解析结果
+type DNSObservation struct {
+	Target  string   `json:"target"`
+	Host    string   `json:"host"`
+	A       []string `json:"a,omitempty"`
+	AAAA    []string `json:"aaaa,omitempty"`
+	CNAME   []string `json:"cname,omitempty"`
+	Packets int      `json:"packets"` // 包含该域名的 DNS 报文数量
+}
+
+// DNSObservations 收集抓包中每个域名的 DNS 解析结果，所有方法都可以在 nil 上调用
+type DNSObservations struct {
+	mutex sync.Mutex
+	hosts []*DNSObservation
+	index map[string]*DNSObservation
+}
+
+func NewDNSObservations() *DNSObservations {
+	return &DNSObservations{index: make(map[string]*DNSObservation)}
+}
+
+// get 返回域名对应的记录，不存在时创建，调用前需要加锁
+func (o *DNSObservations) get(target, host string) *DNSObservation {
+	key := target + "|" + host
+	observation, ok := o.index[key]
+	if !ok {
+		observation = &DNSObservation{Target: target, Host: host}
+		o.index[key] = observation
+		o.hosts = append(o.hosts, observation)
+	}
+	return observation
+}
+
+// addPacket 记录一个包含该域名的 DNS 报文
+func (o *DNSObservations) addPacket(target, host string) {
+	if o == nil {
+		return
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.get(target, host).Packets++
+}
+
+// addAnswer 记录域名的一条 A、AAAA 或 CNAME 响应
+func (o *DNSObservations) addAnswer(target, host string, rrType uint16, value string) {
+	if o == nil {
+		return
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	observation := o.get(target, host)
+	var values *[]string
+	switch rrType {
+	case dns.TypeA:
+		values = &observation.A
+	case dns.TypeAAAA:
+		values = &observation.AAAA
+	case dns.TypeCNAME:
+		values = &observation.CNAME
+	default:
+		return
+	}
+	if !slices.Contains(*values, value) {
+		*values = append(*values, value)
+	}
+}
+
+// List 返回所有域名的解析结果，按发现的顺序排列
+func (o *DNSObservations) List() []*DNSObservation {
+	if o == nil {
+		return nil
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return slices.Clone(o.hosts)
+}
+
+// WriteFile 把每个域名的解析结果以 JSON Lines 格式写入文件
+func (o *DNSObservations) WriteFile(filename string) error {
+	fp, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("can't open pcap output file %s: %w", filename, err)
+	}
+	for _, observation := range o.List() {
+		bs, err := marshalRecord(observation)
+		if err != nil {
+			_ = fp.Close()
+			return err
+		}
+		if _, err := fp.Write(append(bs, '\n')); err != nil {
+			_ = fp.Close()
+			return err
+		}
+	}
+	return fp.Close()
+}
+
+// dnsMessages 从 UDP 或 TCP 负载中取出 DNS 报文，TCP 中每个报文前有 2 字节的长度，一个分段中可能有多个报文
+// 跨分段的报文不做重组，直接忽略
+func dnsMessages(payload []byte, tcp bool) [][]byte {
+	if !tcp {
+		return [][]byte{payload}
+	}
+	messages := make([][]byte, 0, 1)
+	for len(payload) >= 2 {
+		length := int(binary.BigEndian.Uint16(payload[0:2]))
+		if length == 0 || length > len(payload)-2 {
+			break
+		}
+		messages = append(messages, payload[2:2+length])
+		payload = payload[2+length:]
+	}
+	return messages
+}
+
+// dnsName 把 DNS 报文中的域名转换为小写，去掉末尾的点
+func dnsName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// rdataNames 返回资源记录的数据中包含的域名，例如 CNAME 的目标和 MX 的邮件服务器
+func rdataNames(rr dns.RR) []string {
+	switch record := rr.(type) {
+	case *dns.CNAME:
+		return []string{record.Target}
+	case *dns.DNAME:
+		return []string{record.Target}
+	case *dns.NS:
+		return []string{record.Ns}
+	case *dns.MX:
+		return []string{record.Mx}
+	case *dns.PTR:
+		return []string{record.Ptr}
+	case *dns.SRV:
+		return []string{record.Target}
+	case *dns.SOA:
+		return []string{record.Ns, record.Mbox}
+	}
+	return nil
+}
+
+// PcapSource 从 pcap 或 pcapng 格式的抓包文件中提取 DNS 请求和响应中目标的子域名，再通过 DNS 验证
+// 响应中的 A 和 AAAA 记录作为服务保存，所有文件只读取一次，同时匹配所有的目标
+type PcapSource struct {
+	files        []string
+	targets      []string
+	observations *DNSObservations
+
+	once  sync.Once
+	hosts map[string][]PassiveHost // 每个目标提取到的子域名
+	err   error
+}
+
+func newPcapSource(args *AppArgs, run *passiveRun, _ string) (PassiveSource, error) {
+	observations := run.dnsObservations
+	if observations == nil {
+		// 没有设置输出文件时也需要收集解析结果，用于生成服务
+		observations = NewDNSObservations()
+	}
+	return &PcapSource{files: args.PcapFiles, targets: args.Targets, observations: observations}, nil
+}
+
+func (s *PcapSource) Name() string {
+	return "pcap"
+}
+
+func (s *PcapSource) MaxPages() int {
+	return 1
+}
+
+func (s *PcapSource) Search(ctx context.Context, target string, _ int) (*PassivePage, error) {
+	s.once.Do(func() { s.hosts, s.err = s.scan(ctx) })
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &PassivePage{Hosts: s.hosts[target]}, nil
+}
+
+// scan 读取所有的抓包文件，记录每个目标的子域名和解析结果
+func (s *PcapSource) scan(ctx context.Context) (map[string][]PassiveHost, error) {
+	messages, invalid := 0, 0
+	for _, filename := range s.files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		fp, err := os.Open(filename)
+		if err != nil {
+			return nil, fmt.Errorf("can't open pcap file %s: %w", filename, err)
+		}
+		err = readCapture(fp, func(linkType uint32, data []byte) {
+			srcPort, dstPort, payload, tcp, ok := transportPayload(linkType, data)
+			if !ok || !slices.Contains(dnsPorts, srcPort) && !slices.Contains(dnsPorts, dstPort) {
+				return
+			}
+			for _, raw := range dnsMessages(payload, tcp) {
+				msg := new(dns.Msg)
+				if err := msg.Unpack(raw); err != nil {
+					invalid++
+					continue
+				}
+				messages++
+				s.observe(msg)
+			}
+		})
+		_ = fp.Close()
+		if err != nil {
+			return nil, fmt.Errorf("can't read pcap file %s: %w", filename, err)
+		}
+	}
+	logger.Infof("Parsed %d DNS messages in %s, skip %d invalid messages.", messages, strings.Join(s.files, ", "), invalid)
+
+	hosts := make(map[string][]PassiveHost, len(s.targets))
+	for _, observation := range s.observations.List() {
+		if !slices.Contains(s.targets, observation.Target) {
+			continue
+		}
+		addresses := append(slices.Clone(observation.A), observation.AAAA...)
+		if len(addresses) == 0 {
+			hosts[observation.Target] = append(hosts[observation.Target], PassiveHost{Host: observation.Host})
+		}
+		for _, address := range addresses {
+			hosts[observation.Target] = append(hosts[observation.Target], PassiveHost{Host: observation.Host, Service: ServiceRecord{IP: address}})
+		}
+	}
+	return hosts, nil
+}
+
+// observe 记录一个 DNS 报文中所有目标范围内的域名，以及响应中的 A、AAAA 和 CNAME 记录
+func (s *PcapSource) observe(msg *dns.Msg) {
+	names := make([]string, 0, len(msg.Question))
+	for _, question := range msg.Question {
+		names = append(names, dnsName(question.Name))
+	}
+	for _, section := range [][]dns.RR{msg.Answer, msg.Ns, msg.Extra} {
+		for _, rr := range section {
+			names = append(names, dnsName(rr.Header().Name))
+			for _, name := range rdataNames(rr) {
+				names = append(names, dnsName(name))
+			}
+		}
+	}
+
+	for _, target := range s.targets {
+		// 同一个报文中的域名只计数一次
+		counted := make(map[string]bool)
+		for _, name := range names {
+			if counted[name] || !inScope(name, target) || !validHostname(name) {
+				continue
+			}
+			counted[name] = true
+			s.observations.addPacket(target, name)
+		}
+		if !msg.Response {
+			continue
+		}
+		for _, rr := range msg.Answer {
+			name := dnsName(rr.Header().Name)
+			if !inScope(name, target) || !validHostname(name) {
+				continue
+			}
+			switch record := rr.(type) {
+			case *dns.A:
+				s.observations.addAnswer(target, name, dns.TypeA, record.A.String())
+			case *dns.AAAA:
+				s.observations.addAnswer(target, name, dns.TypeAAAA, record.AAAA.String())
+			case *dns.CNAME:
+				s.observations.addAnswer(target, name, dns.TypeCNAME, dnsName(record.Target))
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package enumsubdomain
+
+import (
+	"fmt"
+	"github.com/lightless233/enum-subdomain-go/internal"
+	"strings"
+	"time"
+)
+
+// progressReporter 显示扫描进度，终端上显示进度条，不是终端时定期输出日志
+type progressReporter struct {
+	stats        *ScanStats
+	startTime    time.Time
+	lastTime     time.Time
+	lastResolved uint64
+	rate         float64 // 平滑后的每秒查询数
+}
+
+func newProgressReporter(stats *ScanStats) *progressReporter {
+	now := time.Now()
+	return &progressReporter{stats: stats, startTime: now, lastTime: now}
+}
+
+// run 定期刷新进度，stopChan 关闭后清除进度条
+func (p *progressReporter) run(stopChan chan struct{}) {
+	terminal := internal.IsTerminal()
+	interval := 10 * time.Second
+	if terminal {
+		interval = 200 * time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopChan:
+			if terminal {
+				internal.Console.ClearStatus()
+			}
+			return
+		case <-ticker.C:
+			p.update()
+			if terminal {
+				internal.Console.SetStatus(p.render(internal.TerminalWidth()))
+			} else {
+				logger.Infof("Progress: %s", p.text())
+			}
+		}
+	}
+}
+
+// update 根据两次刷新之间完成的查询数更新速度
+func (p *progressReporter) update() {
+	now := time.Now()
+	resolved := p.stats.TasksResolved.Load()
+	elapsed := now.Sub(p.lastTime).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	current := float64(resolved-p.lastResolved) / elapsed
+	if p.rate == 0 {
+		p.rate = current
+	} else {
+		p.rate = 0.8*p.rate + 0.2*current
+	}
+	p.lastTime = now
+	p.lastResolved = resolved
+}
+
+// percent 完成的百分比，任务总数未知时返回 -1
+func (p *progressReporter) percent() float64 {
+	total := p.stats.TasksTotal.Load()
+	if total == 0 {
+		return -1
+	}
+	percent := float64(p.stats.TasksResolved.Load()) / float64(total) * 100
+	if percent > 100 {
+		percent = 100
+	}
+	return percent
+}
+
+// text 进度的文字描述
+func (p *progressReporter) text() string {
+	resolved := p.stats.TasksResolved.Load()
+	total := p.stats.TasksTotal.Load()
+	elapsed := time.Since(p.startTime).Round(time.Second)
+
+	parts := make([]string, 0, 5)
+	if percent := p.percent(); percent >= 0 {
+		parts = append(parts, fmt.Sprintf("%.1f%% %d/%d", percent, resolved, total))
+	} else {
+		parts = append(parts, fmt.Sprintf("%d resolved", resolved))
+	}
+	parts = append(parts,
+		fmt.Sprintf("%.0f q/s", p.rate),
+		fmt.Sprintf("found %d", p.stats.ResultsFound.Load()),
+		fmt.Sprintf("elapsed %s", elapsed),
+	)
+	if total > resolved && p.rate > 0 {
+		eta := time.Duration(float64(total-resolved) / p.rate * float64(time.Second))
+		parts = append(parts, fmt.Sprintf("ETA %s", eta.Round(time.Second)))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// render 生成终端上显示的进度条，宽度不超过终端宽度
+func (p *progressReporter) render(width int) string {
+	text := p.text()
+	percent := p.percent()
+	barWidth := width - len(text) - 4
+	if percent < 0 || barWidth < 10 {
+		if len(text) >= width {
+			return text[:width-1]
+		}
+		return text
+	}
+	if barWidth > 40 {
+		barWidth = 40
+	}
+
+	filled := int(percent / 100 * float64(barWidth))
+	bar := strings.Repeat("=", filled)
+	if filled < barWidth {
+		bar += ">" + strings.Repeat(" ", barWidth-filled-1)
+	}
+	return fmt.Sprintf("[%s] %s", bar, text)
+}
@@ -0,0 +1,129 @@
+package enumsubdomain
+
+import (
+	"context"
+	"fmt"
+	"github.com/bytedance/sonic"
+	"net/http"
+	netURL "net/url"
+	"strings"
+)
+
+// DefaultQuakeBaseURL 360 Quake API 的默认地址
+const DefaultQuakeBaseURL = "https://quake.360.net"
+
+// quakePageSize Quake 每页的结果数量
+const quakePageSize = 100
+
+// quakeSearchRequest Quake 服务数据查询接口的请求参数
+type quakeSearchRequest struct {
+	Query   string   `json:"query"`
+	Start   int      `json:"start"`
+	Size    int      `json:"size"`
+	Include []string `json:"include"`
+}
+
+// QuakeSearchResponse Quake search/quake_service 接口的返回结果，code 为 0 表示成功
+//
+// 请求结果样例：
+//
+//	{
+//	    "code": 0,
+//	    "message": "Successful.",
+//	    "data": [
+//	        {"ip": "43.129.25.182", "port": 443, "domain": "c1.lightless.me", "service": {"name": "http/ssl"}}
+//	    ],
+//	    "meta": {"pagination": {"count": 1, "page_index": 1, "page_size": 100, "total": 1}}
+//	}
+type QuakeSearchResponse struct {
+	Code    any    `json:"code"` // 成功时为 0，部分错误码是字符串，例如 "q3005"
+	Message string `json:"message"`
+	Data    []struct {
+		IP      string `json:"ip"`
+		Port    int    `json:"port"`
+		Domain  string `json:"domain"`
+		Service struct {
+			Name string `json:"name"`
+		} `json:"service"`
+	} `json:"data"`
+	Meta struct {
+		Pagination struct {
+			Total int `json:"total"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
+
+// success 是否为成功的返回
+func (r *QuakeSearchResponse) success() bool {
+	return fmt.Sprint(r.Code) == "0"
+}
+
+// QuakeSource 360 Quake 数据源，查询语句为 domain: "target"
+type QuakeSource struct {
+	baseURL   string
+	token     string
+	maxPages  int
+	requester *apiRequester
+}
+
+func newQuakeSource(args *AppArgs, _ *passiveRun, baseURL string) (PassiveSource, error) {
+	if _, err := netURL.ParseRequestURI(baseURL); err != nil {
+		return nil, fmt.Errorf("quake base url error: %w", err)
+	}
+	return &QuakeSource{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		token:     args.Credentials["quake"],
+		maxPages:  args.passiveMaxPages(),
+		requester: newAPIRequester("quake", 0),
+	}, nil
+}
+
+func (s *QuakeSource) Name() string {
+	return "quake"
+}
+
+func (s *QuakeSource) MaxPages() int {
+	return s.maxPages
+}
+
+func (s *QuakeSource) Search(ctx context.Context, target string, page int) (*PassivePage, error) {
+	body, err := sonic.Marshal(&quakeSearchRequest{
+		Query:   fmt.Sprintf(`domain: "%s"`, target),
+		Start:   (page - 1) * quakePageSize,
+		Size:    quakePageSize,
+		Include: []string{"ip", "port", "domain", "service.name"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set("X-QuakeToken", s.token)
+	header.Set("Content-Type", "application/json")
+
+	content, err := s.requester.do(ctx, http.MethodPost, s.baseURL+"/api/v3/search/quake_service", header, body)
+	if err != nil {
+		if message := apiErrorMessage(err, "message"); message != "" {
+			return nil, &PassiveAPIError{Source: "quake", Message: message}
+		}
+		return nil, err
+	}
+	response := &QuakeSearchResponse{}
+	if err := sonic.Unmarshal(content, response); err != nil {
+		return nil, fmt.Errorf("error when parse quake response: %w", err)
+	}
+	if !response.success() {
+		return nil, &PassiveAPIError{Source: "quake", Message: fmt.Sprintf("%v %s", response.Code, response.Message)}
+	}
+
+	result := &PassivePage{
+		Hosts: make([]PassiveHost, 0, len(response.Data)),
+		More:  page*quakePageSize < response.Meta.Pagination.Total,
+	}
+	for _, item := range response.Data {
+		result.Hosts = append(result.Hosts, PassiveHost{
+			Host:    item.Domain,
+			Service: ServiceRecord{IP: item.IP, Port: item.Port, Protocol: item.Service.Name},
+		})
+	}
+	return result, nil
+}
@@ -1,17 +1,30 @@
 package enumsubdomain
 
 import (
-	"encoding/csv"
+	"context"
 	"fmt"
-	"os"
+	"net"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 type SubdomainResult struct {
+	target     string // 结果所属的根域名
+	source     string // 发现该域名的 technical
+	foundAt    time.Time
 	dnsResult  *DNSResolveResult
 	httpResult *HTTPResult
+	services   []ServiceRecord // 被动数据源中记录的该域名开放的服务
+	done       func()          // 结果写入所有输出后调用，用于推进 checkpoint，可以为 nil
+}
+
+// markDone 标记结果已经处理完成
+func (r *SubdomainResult) markDone() {
+	if r.done != nil {
+		r.done()
+	}
 }
 
 func (r SubdomainResult) String() string {
@@ -22,21 +35,116 @@ func (r SubdomainResult) String() string {
 	)
 }
 
+// ResultRecord 结构化的扫描结果，用于 JSON / JSON Lines 输出，也方便 SDK 调用方读取结果
+type ResultRecord struct {
+	Target   string          `json:"target"`
+	Domain   string          `json:"domain"`
+	Source   string          `json:"source"`
+	CNAME    []string        `json:"cname"`
+	A        []string        `json:"a"`
+	HTTP     *HTTPRecord     `json:"http,omitempty"`
+	Services []ServiceRecord `json:"services,omitempty"`
+	FoundAt  time.Time       `json:"found_at"`
+}
+
+// ServiceRecord 被动数据源（例如 FOFA）中记录的服务，字段没有获取时为零值
+type ServiceRecord struct {
+	IP       string `json:"ip,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// String 格式为 ip:port/protocol，没有的部分省略
+func (s ServiceRecord) String() string {
+	text := s.IP
+	if s.Port != 0 {
+		text += ":" + strconv.Itoa(s.Port)
+	}
+	if s.Protocol != "" {
+		text += "/" + s.Protocol
+	}
+	return text
+}
+
+// ParseServiceRecord 解析 ip:port/protocol 格式的服务
+func ParseServiceRecord(s string) ServiceRecord {
+	var service ServiceRecord
+	if idx := strings.LastIndex(s, "/"); idx >= 0 {
+		s, service.Protocol = s[:idx], s[idx+1:]
+	}
+	if host, port, err := net.SplitHostPort(s); err == nil {
+		s = host
+		service.Port, _ = strconv.Atoi(port)
+	}
+	service.IP = s
+	return service
+}
+
+// HTTPRecord HTTP 探测的结果，没有开启 --fetch-title 时为 nil
+type HTTPRecord struct {
+	StatusCode uint      `json:"status_code"`
+	Title      string    `json:"title"`
+	Location   string    `json:"location"`
+	BodyLength uint      `json:"body_length"`
+	Error      string    `json:"error,omitempty"`
+	ProbedAt   time.Time `json:"probed_at"`
+}
+
+// Record 把扫描结果转换为 ResultRecord
+func (r *SubdomainResult) Record() *ResultRecord {
+	record := &ResultRecord{
+		Target:  r.target,
+		Domain:  r.dnsResult.domain,
+		Source:  r.source,
+		CNAME:   make([]string, 0, len(r.dnsResult.CNAMERecord)),
+		A:       make([]string, 0, len(r.dnsResult.ARecord)),
+		FoundAt: r.foundAt,
+	}
+	record.CNAME = append(record.CNAME, r.dnsResult.CNAMERecord...)
+	record.A = append(record.A, r.dnsResult.ARecord...)
+	if len(r.services) > 0 {
+		record.Services = append([]ServiceRecord{}, r.services...)
+	}
+
+	if !r.httpResult.probedAt.IsZero() {
+		record.HTTP = &HTTPRecord{
+			StatusCode: r.httpResult.statusCode,
+			Title:      r.httpResult.title,
+			Location:   r.httpResult.location,
+			BodyLength: r.httpResult.bodyLength,
+			Error:      r.httpResult.error,
+			ProbedAt:   r.httpResult.probedAt,
+		}
+	}
+	return record
+}
+
 type ResultEngine struct {
 	mainWG          *sync.WaitGroup
 	waitGroup       *sync.WaitGroup
 	resultChan      chan *SubdomainResult
 	appArgs         *AppArgs
+	stats           *ScanStats
+	checkpointer    *Checkpointer
+	differ          *Differ
+	writers         []OutputWriter
+	cancel          context.CancelFunc
 	subdomainResult []*SubdomainResult
+	err             error
 }
 
-func NewResultEngine(appArgs *AppArgs, mainWG *sync.WaitGroup, resultChan chan *SubdomainResult) *ResultEngine {
+func NewResultEngine(appArgs *AppArgs, stats *ScanStats, checkpointer *Checkpointer, differ *Differ, writers []OutputWriter, cancel context.CancelFunc, mainWG *sync.WaitGroup, resultChan chan *SubdomainResult) *ResultEngine {
 	var wg sync.WaitGroup
 	return &ResultEngine{
 		mainWG:          mainWG,
 		waitGroup:       &wg,
 		resultChan:      resultChan,
 		appArgs:         appArgs,
+		stats:           stats,
+		checkpointer:    checkpointer,
+		differ:          differ,
+		writers:         writers,
+		cancel:          cancel,
 		subdomainResult: make([]*SubdomainResult, 0),
 	}
 }
@@ -49,6 +157,36 @@ func (engine *ResultEngine) Run() {
 	engine.waitGroup.Wait()
 }
 
+// fail 记录第一个错误并取消整个扫描流程，之后的结果仍然会被消费掉，避免上游阻塞
+func (engine *ResultEngine) fail(err error) {
+	logger.Errorf("ResultEngine error: %+v", err)
+	if engine.err == nil {
+		engine.err = err
+		engine.cancel()
+	}
+}
+
+// replayResumed 恢复扫描时，把之前找到的结果重新与之前的扫描对比，使对比结果和 webhook 包含中断前找到的子域名
+// 旧版本的 state 文件只记录了域名，这些域名只能标记为已出现，对比结果中不会包含它们
+func (engine *ResultEngine) replayResumed() {
+	replayed := make(map[string]bool)
+	for _, record := range engine.checkpointer.resumeRecords() {
+		engine.differ.Compare(record)
+		replayed[record.Domain] = true
+	}
+
+	missing := make([]string, 0)
+	for _, domain := range engine.checkpointer.resumeFound() {
+		if !replayed[domain] {
+			missing = append(missing, domain)
+		}
+	}
+	if len(missing) > 0 && engine.differ != nil {
+		logger.Warnf("Checkpoint has no records for %d domains found before the interrupt, they are not included in the diff.", len(missing))
+	}
+	engine.differ.markSeen(missing)
+}
+
 func (engine *ResultEngine) worker() {
 	defer func() {
 		engine.waitGroup.Done()
@@ -57,23 +195,21 @@ func (engine *ResultEngine) worker() {
 		}
 	}()
 
-	// 如果文件名为空，可能是从 CLI 进来的，不写文件
-	var writer *csv.Writer
-	if engine.appArgs.OutputFile != "" {
-		fp, err := os.OpenFile(engine.appArgs.OutputFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_TRUNC, 0644)
-		defer func() { _ = fp.Close() }()
-		if err != nil {
-			logger.Fatalf("Can't open output file to write. filename: %s, error: %+v", engine.appArgs.OutputFile, err)
-			panic(err)
+	// 退出前（包括被中断时）关闭所有的 writer，把缓冲的内容刷到磁盘
+	defer func() {
+		for _, writer := range engine.writers {
+			if err := writer.Close(); err != nil {
+				engine.fail(fmt.Errorf("error when closing output: %w", err))
+			}
 		}
-		writer = csv.NewWriter(fp)
-		_ = writer.Write([]string{
-			"DOMAIN", "CNAME", "A", "STATUS_CODE", "TITLE", "LOCATION", "CONTENT_LENGTH", "HTTP_ERROR",
-		})
-	}
+	}()
 
-	// 加个 buffer 去重使用
+	// 加个 buffer 去重使用，恢复扫描时之前找到的结果已经写在输出文件中了
 	buffer := make(map[string]string)
+	for _, domain := range engine.checkpointer.resumeFound() {
+		buffer[domain] = ""
+	}
+	engine.replayResumed()
 
 	logger.Debugf("ResultEngine start.")
 	for {
@@ -84,37 +220,43 @@ func (engine *ResultEngine) worker() {
 
 		// 过滤掉为空的结果
 		if len(task.dnsResult.ARecord) == 0 && len(task.dnsResult.CNAMERecord) == 0 {
+			task.markDone()
 			continue
 		}
 
 		// 去重逻辑
 		_, ok := buffer[task.dnsResult.domain]
 		if ok {
+			task.markDone()
 			continue
 		} else {
 			buffer[task.dnsResult.domain] = ""
 		}
 
-		// 写入结果文件
-		if engine.appArgs.OutputFile != "" {
-			err := writer.Write([]string{
-				task.dnsResult.domain,
-				strings.Join(task.dnsResult.CNAMERecord, ","),
-				strings.Join(task.dnsResult.ARecord, ","),
-				strconv.Itoa(int(task.httpResult.statusCode)),
-				task.httpResult.title,
-				task.httpResult.location,
-				strconv.Itoa(int(task.httpResult.bodyLength)),
-				task.httpResult.error,
-			})
-			if err != nil {
-				logger.Fatalf("Can't write result file, filename: %s, error: %+v", engine.appArgs.OutputFile, err)
-				panic(err)
+		// 写入所有的输出，出错之后不再继续写入
+		written := engine.err == nil
+		if written {
+			for _, writer := range engine.writers {
+				if err := writer.Write(task); err != nil {
+					engine.fail(fmt.Errorf("error when writing output: %w", err))
+					written = false
+					break
+				}
 			}
-			writer.Flush()
 		}
 
+		// 与之前的结果对比，新增和变化的结果在日志中标记出来
+		record := task.Record()
+		entry := engine.differ.Compare(record)
+
 		engine.subdomainResult = append(engine.subdomainResult, task)
+		engine.stats.ResultsFound.Add(1)
+		metricResults.WithLabelValues(task.source).Inc()
+		// 写入失败的结果不记录到 checkpoint 中，恢复扫描时重新测试
+		if written {
+			engine.checkpointer.addFound(record)
+			task.markDone()
+		}
 
 		// 只有从命令行执行的时候才打印结果
 		if engine.appArgs.FromCLI {
@@ -122,7 +264,11 @@ func (engine *ResultEngine) worker() {
 			//	task.dnsResult.domain, task.dnsResult.CNAMERecord, task.dnsResult.ARecord,
 			//	task.httpResult.statusCode, task.httpResult.title, task.httpResult.location, task.httpResult.bodyLength,
 			//)
-			logger.Info(task.String())
+			if entry != nil && entry.Status != DiffUnchanged {
+				logger.Infof("[%s] %s", entry.Status, task.String())
+			} else {
+				logger.Info(task.String())
+			}
 		}
 	}
 	logger.Debugf("ResultEngine end.")
@@ -0,0 +1,173 @@
+package enumsubdomain
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LoadResultRecords 读取之前输出的结果文件，支持 csv、jsonl、json 和 txt 四种格式
+// 格式根据扩展名判断，txt 和无法判断的扩展名根据文件内容猜测，默认的 out.txt 实际上是 CSV 格式
+func LoadResultRecords(filename string) ([]*ResultRecord, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't read result file %s: %w", filename, err)
+	}
+
+	format := resultFormat(filename, content)
+	records, err := parseResultRecords(content, format)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse result file %s as %s: %w", filename, format, err)
+	}
+	return records, nil
+}
+
+// resultFormat 根据扩展名和文件内容判断结果文件的格式
+func resultFormat(filename string, content []byte) string {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	if format != "csv" && format != "jsonl" && format != "json" {
+		format = guessResultFormat(content)
+	}
+	return format
+}
+
+// parseResultRecords 按指定的格式解析结果文件的内容
+func parseResultRecords(content []byte, format string) ([]*ResultRecord, error) {
+	switch format {
+	case "csv":
+		return readCSVRecords(bytes.NewReader(content))
+	case "jsonl":
+		return readJSONLRecords(bytes.NewReader(content))
+	case "json":
+		var records []*ResultRecord
+		if err := json.Unmarshal(content, &records); err != nil {
+			return nil, err
+		}
+		return records, nil
+	default:
+		return readHostRecords(bytes.NewReader(content))
+	}
+}
+
+// guessResultFormat 根据文件内容猜测结果文件的格式
+func guessResultFormat(content []byte) string {
+	trimmed := bytes.TrimSpace(content)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		return "json"
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return "jsonl"
+	case bytes.Contains(bytes.SplitN(trimmed, []byte("\n"), 2)[0], []byte("DOMAIN")):
+		return "csv"
+	default:
+		return "txt"
+	}
+}
+
+// readCSVRecords 按表头读取 CSV 结果，兼容没有 TARGET 列的旧格式
+func readCSVRecords(reader io.Reader) ([]*ResultRecord, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return []*ResultRecord{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for idx, name := range header {
+		columns[strings.ToUpper(strings.TrimSpace(name))] = idx
+	}
+	if _, ok := columns["DOMAIN"]; !ok {
+		return nil, fmt.Errorf("DOMAIN column not found")
+	}
+
+	records := make([]*ResultRecord, 0)
+	for {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		column := func(name string) string {
+			if idx, ok := columns[name]; ok && idx < len(row) {
+				return row[idx]
+			}
+			return ""
+		}
+		splitRecords := func(value string) []string {
+			if value == "" {
+				return []string{}
+			}
+			return strings.Split(value, ",")
+		}
+
+		record := &ResultRecord{
+			Target: column("TARGET"),
+			Domain: column("DOMAIN"),
+			CNAME:  splitRecords(column("CNAME")),
+			A:      splitRecords(column("A")),
+		}
+
+		// CSV 中没有探测过 HTTP 的结果状态码为 0
+		statusCode, _ := strconv.Atoi(column("STATUS_CODE"))
+		if statusCode != 0 || column("HTTP_ERROR") != "" {
+			bodyLength, _ := strconv.Atoi(column("CONTENT_LENGTH"))
+			record.HTTP = &HTTPRecord{
+				StatusCode: uint(statusCode),
+				Title:      column("TITLE"),
+				Location:   column("LOCATION"),
+				BodyLength: uint(bodyLength),
+				Error:      column("HTTP_ERROR"),
+			}
+		}
+		for _, service := range splitRecords(column("SERVICES")) {
+			record.Services = append(record.Services, ParseServiceRecord(service))
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// readJSONLRecords 读取每行一个 JSON 对象的结果
+func readJSONLRecords(reader io.Reader) ([]*ResultRecord, error) {
+	records := make([]*ResultRecord, 0)
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record ResultRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+	return records, scanner.Err()
+}
+
+// readHostRecords 读取每行一个域名的结果
+func readHostRecords(reader io.Reader) ([]*ResultRecord, error) {
+	records := make([]*ResultRecord, 0)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		domain := strings.TrimSpace(scanner.Text())
+		if domain == "" || strings.HasPrefix(domain, "#") {
+			continue
+		}
+		records = append(records, &ResultRecord{Domain: domain, CNAME: []string{}, A: []string{}})
+	}
+	return records, scanner.Err()
+}
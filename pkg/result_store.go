@@ -0,0 +1,290 @@
+package enumsubdomain
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	_ "modernc.org/sqlite"
+	"strings"
+	"time"
+)
+
+// storeSchema SQLite 中保存历史结果的表结构
+// subdomains 记录每个子域名第一次和最后一次出现的时间，其余的表按 run 记录每次扫描的结果
+const storeSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    targets      TEXT    NOT NULL,
+    technicals   TEXT    NOT NULL,
+    status       TEXT    NOT NULL,
+    started_at   TEXT    NOT NULL,
+    finished_at  TEXT,
+    result_count INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS subdomains (
+    domain       TEXT    PRIMARY KEY,
+    target       TEXT    NOT NULL,
+    first_seen   TEXT    NOT NULL,
+    last_seen    TEXT    NOT NULL,
+    first_run_id INTEGER NOT NULL,
+    last_run_id  INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS run_results (
+    run_id   INTEGER NOT NULL,
+    domain   TEXT    NOT NULL,
+    target   TEXT    NOT NULL,
+    source   TEXT    NOT NULL,
+    found_at TEXT    NOT NULL,
+    PRIMARY KEY (run_id, domain)
+);
+CREATE TABLE IF NOT EXISTS dns_records (
+    run_id INTEGER NOT NULL,
+    domain TEXT    NOT NULL,
+    type   TEXT    NOT NULL,
+    value  TEXT    NOT NULL,
+    PRIMARY KEY (run_id, domain, type, value)
+);
+CREATE TABLE IF NOT EXISTS http_probes (
+    run_id      INTEGER NOT NULL,
+    domain      TEXT    NOT NULL,
+    status_code INTEGER NOT NULL,
+    title       TEXT    NOT NULL,
+    location    TEXT    NOT NULL,
+    body_length INTEGER NOT NULL,
+    error       TEXT    NOT NULL,
+    probed_at   TEXT    NOT NULL,
+    PRIMARY KEY (run_id, domain)
+);
+CREATE INDEX IF NOT EXISTS idx_subdomains_target ON subdomains (target);
+CREATE INDEX IF NOT EXISTS idx_run_results_domain ON run_results (domain);
+`
+
+// 扫描记录的状态
+const (
+	RunStatusRunning     = "running"
+	RunStatusFinished    = "finished"
+	RunStatusInterrupted = "interrupted"
+)
+
+// ResultStore 基于 SQLite 的结果库，保存每次扫描的结果以及子域名的首次、最近出现时间
+// 实现了 OutputWriter，可以直接作为 ResultEngine 的输出
+type ResultStore struct {
+	db     *sql.DB
+	runID  int64
+	count  int64
+	runCtx context.Context
+}
+
+// OpenResultStore 打开（或创建）结果库
+func OpenResultStore(filename string) (*ResultStore, error) {
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't open result store %s: %w", filename, err)
+	}
+	// SQLite 同一时间只允许一个写入者，避免出现 database is locked
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(storeSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("can't init result store %s: %w", filename, err)
+	}
+	return &ResultStore{db: db}, nil
+}
+
+// storeTimeLayout 固定小数位数的 RFC3339 格式，RFC3339Nano 会去掉末尾的 0，长度不同时不能按字符串比较
+const storeTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// formatTime 统一使用固定长度的 UTC 时间保存，方便直接用 SQL 比较
+func formatTime(t time.Time) string {
+	return t.UTC().Format(storeTimeLayout)
+}
+
+// BeginRun 新建一条扫描记录，之后写入的结果都归属于这次扫描
+// runCtx 被取消时，这次扫描在结束时会被标记为 interrupted
+func (s *ResultStore) BeginRun(runCtx context.Context, args *AppArgs) error {
+	result, err := s.db.Exec(
+		"INSERT INTO runs (targets, technicals, status, started_at) VALUES (?, ?, ?, ?)",
+		strings.Join(args.Targets, ","), strings.Join(args.Technicals, ","), RunStatusRunning, formatTime(time.Now()),
+	)
+	if err != nil {
+		return fmt.Errorf("can't create run in result store: %w", err)
+	}
+	s.runID, err = result.LastInsertId()
+	s.runCtx = runCtx
+	return err
+}
+
+// RunID 当前扫描在结果库中的编号
+func (s *ResultStore) RunID() int64 {
+	return s.runID
+}
+
+func (s *ResultStore) Write(result *SubdomainResult) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	domain := result.dnsResult.domain
+	foundAt := formatTime(result.foundAt)
+
+	// 多个扫描同时写入时结果的顺序不确定，只在时间更晚时更新最后发现的时间和目标，更早时更新首次发现的时间
+	_, err = tx.Exec(`
+		INSERT INTO subdomains (domain, target, first_seen, last_seen, first_run_id, last_run_id) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (domain) DO UPDATE SET
+			target = CASE WHEN excluded.last_seen >= last_seen THEN excluded.target ELSE target END,
+			first_seen = min(first_seen, excluded.first_seen),
+			last_seen = max(last_seen, excluded.last_seen),
+			first_run_id = min(first_run_id, excluded.first_run_id),
+			last_run_id = max(last_run_id, excluded.last_run_id)`,
+		domain, result.target, foundAt, foundAt, s.runID, s.runID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"INSERT OR REPLACE INTO run_results (run_id, domain, target, source, found_at) VALUES (?, ?, ?, ?, ?)",
+		s.runID, domain, result.target, result.source, foundAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	records := map[string][]string{"A": result.dnsResult.ARecord, "CNAME": result.dnsResult.CNAMERecord}
+	for recordType, values := range records {
+		for _, value := range values {
+			_, err = tx.Exec(
+				"INSERT OR IGNORE INTO dns_records (run_id, domain, type, value) VALUES (?, ?, ?, ?)",
+				s.runID, domain, recordType, value,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if probe := result.httpResult; !probe.probedAt.IsZero() {
+		_, err = tx.Exec(`
+			INSERT OR REPLACE INTO http_probes (run_id, domain, status_code, title, location, body_length, error, probed_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			s.runID, domain, probe.statusCode, probe.title, probe.location, probe.bodyLength, probe.error, formatTime(probe.probedAt),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.count++
+	return nil
+}
+
+// Close 记录扫描的结束状态并关闭数据库
+func (s *ResultStore) Close() error {
+	if s.runID != 0 {
+		status := RunStatusFinished
+		if s.runCtx != nil && s.runCtx.Err() != nil {
+			status = RunStatusInterrupted
+		}
+		_, err := s.db.Exec(
+			"UPDATE runs SET status = ?, finished_at = ?, result_count = ? WHERE id = ?",
+			status, formatTime(time.Now()), s.count, s.runID,
+		)
+		if err != nil {
+			_ = s.db.Close()
+			return fmt.Errorf("can't finish run in result store: %w", err)
+		}
+	}
+	return s.db.Close()
+}
+
+// LatestRunID 返回扫描目标相同、正常结束的最近一次扫描的编号，没有时返回 0
+func (s *ResultStore) LatestRunID(targets []string) (int64, error) {
+	var runID int64
+	err := s.db.QueryRow(
+		"SELECT id FROM runs WHERE targets = ? AND status = ? ORDER BY id DESC LIMIT 1",
+		strings.Join(targets, ","), RunStatusFinished,
+	).Scan(&runID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return runID, err
+}
+
+// RunRecords 读取某次扫描的所有结果
+func (s *ResultStore) RunRecords(runID int64) ([]*ResultRecord, error) {
+	var exists int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM runs WHERE id = ?", runID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		return nil, fmt.Errorf("run %d not found in result store", runID)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT r.domain, r.target, r.source, r.found_at,
+		       p.status_code, p.title, p.location, p.body_length, p.error, p.probed_at
+		FROM run_results r LEFT JOIN http_probes p ON p.run_id = r.run_id AND p.domain = r.domain
+		WHERE r.run_id = ? ORDER BY r.domain`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	records := make([]*ResultRecord, 0)
+	recordMap := make(map[string]*ResultRecord)
+	for rows.Next() {
+		var foundAt string
+		var statusCode, bodyLength sql.NullInt64
+		var title, location, probeError, probedAt sql.NullString
+		record := &ResultRecord{CNAME: []string{}, A: []string{}}
+		err := rows.Scan(&record.Domain, &record.Target, &record.Source, &foundAt,
+			&statusCode, &title, &location, &bodyLength, &probeError, &probedAt)
+		if err != nil {
+			return nil, err
+		}
+		record.FoundAt, _ = time.Parse(time.RFC3339Nano, foundAt)
+		if probedAt.Valid {
+			record.HTTP = &HTTPRecord{
+				StatusCode: uint(statusCode.Int64),
+				Title:      title.String,
+				Location:   location.String,
+				BodyLength: uint(bodyLength.Int64),
+				Error:      probeError.String,
+			}
+			record.HTTP.ProbedAt, _ = time.Parse(time.RFC3339Nano, probedAt.String)
+		}
+		records = append(records, record)
+		recordMap[record.Domain] = record
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	dnsRows, err := s.db.Query("SELECT domain, type, value FROM dns_records WHERE run_id = ? ORDER BY domain, type, value", runID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = dnsRows.Close() }()
+	for dnsRows.Next() {
+		var domain, recordType, value string
+		if err := dnsRows.Scan(&domain, &recordType, &value); err != nil {
+			return nil, err
+		}
+		record, ok := recordMap[domain]
+		if !ok {
+			continue
+		}
+		if recordType == "CNAME" {
+			record.CNAME = append(record.CNAME, value)
+		} else {
+			record.A = append(record.A, value)
+		}
+	}
+	return records, dnsRows.Err()
+}
@@ -0,0 +1,93 @@
+package enumsubdomain
+
+import (
+	"context"
+	"fmt"
+	"github.com/bytedance/sonic"
+	"net/http"
+	netURL "net/url"
+	"strings"
+	"time"
+)
+
+// DefaultSecurityTrailsBaseURL SecurityTrails API 的默认地址
+const DefaultSecurityTrailsBaseURL = "https://api.securitytrails.com"
+
+// securityTrailsInterval SecurityTrails 限制每秒的请求数
+const securityTrailsInterval = time.Second
+
+// SecurityTrailsSubdomainsResponse SecurityTrails domain/{domain}/subdomains 接口的返回结果，subdomains 中只有子域名的前缀
+//
+// 请求结果样例：
+//
+//	{
+//	    "endpoint": "/v1/domain/lightless.me/subdomains",
+//	    "meta": {"limit_reached": false},
+//	    "subdomain_count": 2,
+//	    "subdomains": ["c1", "ss"]
+//	}
+type SecurityTrailsSubdomainsResponse struct {
+	Endpoint       string   `json:"endpoint"`
+	SubdomainCount int      `json:"subdomain_count"`
+	Subdomains     []string `json:"subdomains"`
+	Meta           struct {
+		LimitReached bool `json:"limit_reached"` // 结果数量超过了账号的限制，只返回了一部分
+	} `json:"meta"`
+}
+
+// SecurityTrailsSource SecurityTrails 数据源，子域名接口一次返回所有结果，没有分页
+type SecurityTrailsSource struct {
+	baseURL   string
+	key       string
+	requester *apiRequester
+}
+
+func newSecurityTrailsSource(args *AppArgs, _ *passiveRun, baseURL string) (PassiveSource, error) {
+	if _, err := netURL.ParseRequestURI(baseURL); err != nil {
+		return nil, fmt.Errorf("securitytrails base url error: %w", err)
+	}
+	return &SecurityTrailsSource{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		key:       args.Credentials["securitytrails"],
+		requester: newAPIRequester("securitytrails", securityTrailsInterval),
+	}, nil
+}
+
+func (s *SecurityTrailsSource) Name() string {
+	return "securitytrails"
+}
+
+func (s *SecurityTrailsSource) MaxPages() int {
+	return 1
+}
+
+func (s *SecurityTrailsSource) Search(ctx context.Context, target string, _ int) (*PassivePage, error) {
+	params := netURL.Values{}
+	params.Set("children_only", "false")
+	params.Set("include_inactive", "true")
+	header := http.Header{}
+	header.Set("APIKEY", s.key)
+
+	url := fmt.Sprintf("%s/v1/domain/%s/subdomains?%s", s.baseURL, netURL.PathEscape(target), params.Encode())
+	content, err := s.requester.do(ctx, http.MethodGet, url, header, nil)
+	if err != nil {
+		if message := apiErrorMessage(err, "message"); message != "" {
+			return nil, &PassiveAPIError{Source: "securitytrails", Message: message}
+		}
+		return nil, err
+	}
+	response := &SecurityTrailsSubdomainsResponse{}
+	if err := sonic.Unmarshal(content, response); err != nil {
+		return nil, fmt.Errorf("error when parse securitytrails response: %w", err)
+	}
+	if response.Meta.LimitReached {
+		logger.Warnf("SecurityTrails returned only %d of %d subdomains for %s, limit of the account reached.",
+			len(response.Subdomains), response.SubdomainCount, target)
+	}
+
+	result := &PassivePage{Hosts: make([]PassiveHost, 0, len(response.Subdomains))}
+	for _, prefix := range response.Subdomains {
+		result.Hosts = append(result.Hosts, PassiveHost{Host: prefix + "." + target})
+	}
+	return result, nil
+}
@@ -0,0 +1,685 @@
+package enumsubdomain
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServeArgs serve 命令的参数
+type ServeArgs struct {
+	Listen      string            // 监听地址
+	Concurrency uint              // 同时执行的任务数，超出的任务排队等待
+	APIToken    string            // 不为空时，请求需要带上 Authorization: Bearer <token>
+	Credentials map[string]string // 任务中没有指定凭据时使用的凭据
+	DictDir     string            // 任务中的 dict_file 只能是该目录下的相对路径，为空时不允许任务指定字典文件
+	MaxJobs     uint              // 最多保留的任务数，超出时删除最早结束的任务及其结果，为 0 时使用 DefaultMaxJobs
+}
+
+// DefaultMaxJobs serve 命令默认最多保留的任务数
+const DefaultMaxJobs = 100
+
+// 扫描任务的状态
+const (
+	JobQueued   = "queued"
+	JobRunning  = "running"
+	JobFinished = "finished"
+	JobFailed   = "failed"
+	JobCanceled = "canceled"
+)
+
+// JobRequest 提交扫描任务的参数，与 AppArgs 中的扫描参数对应，输出由服务端保存，不能指定输出文件
+type JobRequest struct {
+	Target        string   `json:"target"`
+	Targets       []string `json:"targets"`
+	Technicals    string   `json:"technicals"`   // DL、D,L 或者 dict,hunter，默认为 DL
+	DictFile      string   `json:"dict_file"`    // 服务端 --dict-dir 目录下的相对路径
+	BruteLength   string   `json:"brute_length"` // 默认为 1-3
+	FofaToken     string   `json:"fofa_token,omitempty"`
+	FofaQuery     string   `json:"fofa_query"` // 默认为 domain="{target}"
+	FofaFields    []string `json:"fofa_fields"`
+	FofaPageSize  uint     `json:"fofa_page_size"`
+	FofaPages     uint     `json:"fofa_pages"`
+	PassivePages  uint     `json:"passive_pages"` // 除 FOFA 以外的被动数据源查询的页数，默认为 10
+	TaskCount     uint     `json:"task_count"`    // 默认为 2 * CPU + 1
+	CheckWildcard *bool    `json:"check_wildcard"`
+	Nameservers   []string `json:"nameservers"`
+	FetchTitle    bool     `json:"fetch_title"`
+}
+
+// appArgs 把任务参数转换为 AppArgs，缺省的参数使用与命令行相同的默认值
+// 字典文件只能从 dictDir 目录中选择，避免调用方读取服务端的任意文件并通过 DNS 查询发送出去
+func (r *JobRequest) appArgs(dictDir string) (*AppArgs, error) {
+	args := &AppArgs{
+		Target:        r.Target,
+		Targets:       r.Targets,
+		BruteLength:   r.BruteLength,
+		FofaToken:     r.FofaToken,
+		FofaQuery:     r.FofaQuery,
+		FofaFields:    r.FofaFields,
+		FofaPageSize:  r.FofaPageSize,
+		FofaPages:     r.FofaPages,
+		PassivePages:  r.PassivePages,
+		TaskCount:     r.TaskCount,
+		CheckWildcard: true,
+		Nameserver:    r.Nameservers,
+		FetchTitle:    r.FetchTitle,
+		// 日志已经由 serve 命令初始化过了，避免每个任务重复初始化
+		FromCLI: true,
+	}
+
+	if r.DictFile != "" {
+		if dictDir == "" {
+			return nil, fmt.Errorf("dict_file is not allowed, the server is started without --dict-dir")
+		}
+		if !filepath.IsLocal(r.DictFile) {
+			return nil, fmt.Errorf("dict_file must be a relative path in the dict directory: %s", r.DictFile)
+		}
+		args.DictFile = filepath.Join(dictDir, r.DictFile)
+	}
+
+	technicals := r.Technicals
+	if technicals == "" {
+		technicals = "DL"
+	}
+	var err error
+	if args.Technicals, err = ParseTechnicals(technicals); err != nil {
+		return nil, err
+	}
+	if args.BruteLength == "" {
+		args.BruteLength = "1-3"
+	}
+	if args.TaskCount == 0 {
+		args.TaskCount = uint(2*runtime.NumCPU() + 1)
+	}
+	if r.CheckWildcard != nil {
+		args.CheckWildcard = *r.CheckWildcard
+	}
+	// 本地数据源需要服务端的文件路径，任务中不能指定
+	for _, tech := range args.Technicals {
+		if info := passiveSourceByTechnical(tech); info != nil && info.baseURL == "" {
+			return nil, fmt.Errorf("technical %s (%s) reads local files and is not supported in jobs", tech, info.name)
+		}
+	}
+
+	// 与命令行一样校验并整理目标，无效的目标在提交时就返回错误
+	if args.Target != "" {
+		if args.Target, err = NormalizeTarget(args.Target); err != nil {
+			return nil, err
+		}
+	}
+	args.Targets = make([]string, 0, len(r.Targets))
+	for _, raw := range r.Targets {
+		target, err := NormalizeTarget(raw)
+		if err != nil {
+			return nil, err
+		}
+		args.Targets = append(args.Targets, target)
+	}
+	if args.Target == "" && len(args.Targets) == 0 {
+		return nil, fmt.Errorf("target can't be empty")
+	}
+	return args, nil
+}
+
+// Job 一个扫描任务
+type Job struct {
+	mutex      sync.Mutex
+	id         string
+	status     string
+	request    JobRequest
+	createdAt  time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+	err        string
+	app        *App
+	cancel     context.CancelFunc
+	results    []*ResultRecord
+	done       chan struct{}
+	// 订阅实时结果的连接，缓冲区满了的订阅者会被断开，避免阻塞 ResultEngine
+	subscribers map[chan *ResultRecord]struct{}
+}
+
+// JobProgress 任务的进度
+type JobProgress struct {
+	TasksGenerated uint64           `json:"tasks_generated"`
+	TasksResolved  uint64           `json:"tasks_resolved"`
+	TasksQueued    uint64           `json:"tasks_queued"` // 已生成但还没有完成解析的任务数
+	ResultsFound   uint64           `json:"results_found"`
+	Resolvers      []ResolverHealth `json:"resolvers,omitempty"`
+}
+
+// JobView 返回给调用方的任务信息
+type JobView struct {
+	ID         string      `json:"id"`
+	Status     string      `json:"status"`
+	Request    JobRequest  `json:"request"`
+	CreatedAt  time.Time   `json:"created_at"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Progress   JobProgress `json:"progress"`
+}
+
+func (job *Job) view() *JobView {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	view := &JobView{
+		ID:        job.id,
+		Status:    job.status,
+		Request:   job.request,
+		CreatedAt: job.createdAt,
+		Error:     job.err,
+	}
+	// 不返回 FOFA 的 token
+	if view.Request.FofaToken != "" {
+		view.Request.FofaToken = redact(view.Request.FofaToken)
+	}
+	if !job.startedAt.IsZero() {
+		startedAt := job.startedAt
+		view.StartedAt = &startedAt
+	}
+	if !job.finishedAt.IsZero() {
+		finishedAt := job.finishedAt
+		view.FinishedAt = &finishedAt
+	}
+	if job.app != nil {
+		stats := job.app.Stats()
+		view.Progress = JobProgress{
+			TasksGenerated: stats.TasksGenerated.Load(),
+			TasksResolved:  stats.TasksResolved.Load(),
+			ResultsFound:   stats.ResultsFound.Load(),
+			Resolvers:      stats.Resolvers.Snapshot(),
+		}
+		if view.Progress.TasksGenerated > view.Progress.TasksResolved {
+			view.Progress.TasksQueued = view.Progress.TasksGenerated - view.Progress.TasksResolved
+		}
+	}
+	return view
+}
+
+// finished 判断任务是否已经结束
+func (job *Job) finished() bool {
+	select {
+	case <-job.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Results 返回任务目前已经找到的结果，任务进行中也可以调用
+func (job *Job) Results() []*ResultRecord {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	return append([]*ResultRecord{}, job.results...)
+}
+
+// passiveFindings 返回任务目前记录的被动发现，任务还没有开始时为空
+func (job *Job) passiveFindings() []*PassiveFinding {
+	job.mutex.Lock()
+	app := job.app
+	job.mutex.Unlock()
+	if app == nil {
+		return []*PassiveFinding{}
+	}
+	if findings := app.PassiveFindings(); findings != nil {
+		return findings
+	}
+	return []*PassiveFinding{}
+}
+
+// Write 作为 App 的 OutputWriter，实时收集任务的结果并推送给所有的订阅者
+func (job *Job) Write(result *SubdomainResult) error {
+	record := result.Record()
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	job.results = append(job.results, record)
+	for subscriber := range job.subscribers {
+		select {
+		case subscriber <- record:
+		default:
+			delete(job.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+	return nil
+}
+
+// subscribe 订阅任务的实时结果，同时返回订阅之前已经找到的结果，两者之间不会遗漏或者重复
+func (job *Job) subscribe() (chan *ResultRecord, []*ResultRecord) {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	subscriber := make(chan *ResultRecord, 256)
+	job.subscribers[subscriber] = struct{}{}
+	return subscriber, append([]*ResultRecord{}, job.results...)
+}
+
+// unsubscribe 取消订阅，订阅者已经因为太慢被断开时不做任何事
+func (job *Job) unsubscribe(subscriber chan *ResultRecord) {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	if _, ok := job.subscribers[subscriber]; ok {
+		delete(job.subscribers, subscriber)
+		close(subscriber)
+	}
+}
+
+func (job *Job) Close() error {
+	return nil
+}
+
+// Server 通过 HTTP API 提交和管理扫描任务，任务通过 App 执行，同时执行的任务数受 Concurrency 限制
+type Server struct {
+	serveArgs *ServeArgs
+	ctx       context.Context
+	mutex     sync.Mutex
+	jobs      map[string]*Job
+	jobOrder  []string
+	nextID    uint64
+	slots     chan struct{}
+	waitGroup sync.WaitGroup
+}
+
+func NewServer(ctx context.Context, serveArgs *ServeArgs) *Server {
+	concurrency := serveArgs.Concurrency
+	if concurrency == 0 {
+		concurrency = 1
+	}
+	return &Server{
+		serveArgs: serveArgs,
+		ctx:       ctx,
+		jobs:      make(map[string]*Job),
+		jobOrder:  make([]string, 0),
+		slots:     make(chan struct{}, concurrency),
+	}
+}
+
+// Submit 提交一个扫描任务，任务在后台排队执行
+func (s *Server) Submit(request JobRequest) (*Job, error) {
+	if _, err := request.appArgs(s.serveArgs.DictDir); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	s.nextID++
+	ctx, cancel := context.WithCancel(s.ctx)
+	job := &Job{
+		id:        strconv.FormatUint(s.nextID, 10),
+		status:    JobQueued,
+		request:   request,
+		createdAt: time.Now(),
+		cancel:    cancel,
+		results:   make([]*ResultRecord, 0),
+		done:      make(chan struct{}),
+
+		subscribers: make(map[chan *ResultRecord]struct{}),
+	}
+	s.jobs[job.id] = job
+	s.jobOrder = append(s.jobOrder, job.id)
+	s.evictJobs()
+	s.mutex.Unlock()
+
+	s.waitGroup.Add(1)
+	go s.runJob(ctx, job)
+	return job, nil
+}
+
+// evictJobs 任务数超过 MaxJobs 时，从最早提交的任务开始删除已经结束的任务，调用前需要加锁
+// 排队和执行中的任务不会被删除，所以任务数可能暂时超过 MaxJobs
+func (s *Server) evictJobs() {
+	maxJobs := int(s.serveArgs.MaxJobs)
+	if maxJobs == 0 {
+		maxJobs = DefaultMaxJobs
+	}
+	if len(s.jobOrder) <= maxJobs {
+		return
+	}
+
+	excess := len(s.jobOrder) - maxJobs
+	kept := make([]string, 0, maxJobs)
+	for _, id := range s.jobOrder {
+		if excess > 0 && s.jobs[id].finished() {
+			delete(s.jobs, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.jobOrder = kept
+}
+
+// Job 根据 id 获取任务
+func (s *Server) Job(id string) *Job {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.jobs[id]
+}
+
+// Jobs 按提交顺序返回所有的任务
+func (s *Server) Jobs() []*Job {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	jobs := make([]*Job, 0, len(s.jobOrder))
+	for _, id := range s.jobOrder {
+		jobs = append(jobs, s.jobs[id])
+	}
+	return jobs
+}
+
+// Cancel 取消任务，排队中的任务直接结束，执行中的任务等待正在进行的查询完成后结束，已找到的结果会保留
+func (s *Server) Cancel(id string) *Job {
+	job := s.Job(id)
+	if job != nil {
+		job.cancel()
+	}
+	return job
+}
+
+func (s *Server) runJob(ctx context.Context, job *Job) {
+	defer s.waitGroup.Done()
+	defer close(job.done)
+	defer job.cancel()
+
+	// 等待空闲的位置
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		job.mutex.Lock()
+		job.status = JobCanceled
+		job.finishedAt = time.Now()
+		job.mutex.Unlock()
+		return
+	}
+
+	// 提交时已经校验过参数了
+	args, _ := job.request.appArgs(s.serveArgs.DictDir)
+	args.applyCredentials(s.serveArgs.Credentials)
+	app := NewApp(args)
+	app.AddOutputWriter(job)
+
+	job.mutex.Lock()
+	job.status = JobRunning
+	job.startedAt = time.Now()
+	job.app = app
+	job.mutex.Unlock()
+	logger.Infof("Job %s started.", job.id)
+
+	_, err := app.RunContext(ctx)
+
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	job.finishedAt = time.Now()
+	switch {
+	case errors.Is(err, context.Canceled):
+		job.status = JobCanceled
+	case err != nil:
+		job.status = JobFailed
+		job.err = err.Error()
+	default:
+		job.status = JobFinished
+	}
+	logger.Infof("Job %s %s.", job.id, job.status)
+}
+
+// Run 启动 HTTP 服务，ctx 被取消后停止服务并等待所有的任务退出
+func (s *Server) Run() error {
+	httpServer := &http.Server{Addr: s.serveArgs.Listen, Handler: s.Handler()}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- httpServer.ListenAndServe()
+	}()
+	logger.Infof("API server listening on %s, concurrency: %d", s.serveArgs.Listen, cap(s.slots))
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-s.ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := httpServer.Shutdown(shutdownCtx)
+	s.waitGroup.Wait()
+	return err
+}
+
+// Handler 返回 API 的路由
+//
+//	POST   /jobs                 提交任务
+//	GET    /jobs                 列出所有任务
+//	GET    /jobs/{id}            获取任务状态和进度
+//	DELETE /jobs/{id}            取消任务，也可以 POST /jobs/{id}/cancel
+//	GET    /jobs/{id}/results    获取结果，format 参数支持 json、jsonl、csv
+//	GET    /jobs/{id}/passive    获取只存在于被动数据源中的发现（无法解析、只有 IP、目标以外的记录）
+//	GET    /jobs/{id}/events     以 Server-Sent Events 的形式推送实时结果和任务进度，直到任务结束
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", s.handleJobs)
+	mux.HandleFunc("/jobs/", s.handleJob)
+	return s.authenticate(mux)
+}
+
+// authenticate 设置了 APIToken 时校验请求的 token
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if s.serveArgs.APIToken == "" {
+		return next
+	}
+	expected := []byte("Bearer " + s.serveArgs.APIToken)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid api token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	bs, _ := marshalRecord(value)
+	_, _ = w.Write(append(bs, '\n'))
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, map[string]string{"error": err.Error()})
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		views := make([]*JobView, 0)
+		for _, job := range s.Jobs() {
+			views = append(views, job.view())
+		}
+		writeJSON(w, http.StatusOK, views)
+
+	case http.MethodPost:
+		var request JobRequest
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("request body error: %w", err))
+			return
+		}
+		job, err := s.Submit(request)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		w.Header().Set("Location", "/jobs/"+job.id)
+		writeJSON(w, http.StatusCreated, job.view())
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	// 路径格式为 /jobs/{id} 或者 /jobs/{id}/{action}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/"), "/")
+	if len(parts) > 2 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("path %s not found", r.URL.Path))
+		return
+	}
+	job := s.Job(parts[0])
+	if job == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %s not found", parts[0]))
+		return
+	}
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	allowed := map[string][]string{
+		"":        {http.MethodGet, http.MethodDelete},
+		"cancel":  {http.MethodPost},
+		"results": {http.MethodGet},
+		"passive": {http.MethodGet},
+		"events":  {http.MethodGet},
+	}
+	methods, ok := allowed[action]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("path %s not found", r.URL.Path))
+		return
+	}
+	if !slices.Contains(methods, r.Method) {
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, job.view())
+	case action == "" || action == "cancel":
+		s.Cancel(job.id)
+		writeJSON(w, http.StatusAccepted, job.view())
+	case action == "results":
+		s.writeResults(w, r, job)
+	case action == "passive":
+		writeJSON(w, http.StatusOK, job.passiveFindings())
+	case action == "events":
+		s.streamEvents(w, r, job)
+	}
+}
+
+// writeResults 按照 format 参数输出任务的结果，任务进行中返回目前已经找到的结果
+func (s *Server) writeResults(w http.ResponseWriter, r *http.Request, job *Job) {
+	results := job.Results()
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		writeJSON(w, http.StatusOK, results)
+	case "jsonl":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, record := range results {
+			bs, _ := marshalRecord(record)
+			_, _ = w.Write(append(bs, '\n'))
+		}
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=job-%s.csv", job.id))
+		writer := csv.NewWriter(w)
+		_ = writer.Write(csvHeader)
+		for _, record := range results {
+			_ = writer.Write(csvRow(record))
+		}
+		writer.Flush()
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("result format error: %s, only json, jsonl, csv allowed", format))
+	}
+}
+
+// streamEvents 以 Server-Sent Events 的形式推送任务的实时结果和进度，任务结束时推送 done 事件后关闭连接
+// 每个结果是一个 result 事件，每秒推送一次 progress 事件，包括任务数和每个 nameserver 的健康状况
+// 默认先推送连接之前已经找到的结果，replay=0 时只推送新的结果，results=0 时只推送进度
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request, job *Job) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	var subscriber chan *ResultRecord
+	var replay []*ResultRecord
+	if r.URL.Query().Get("results") != "0" {
+		subscriber, replay = job.subscribe()
+		defer job.unsubscribe(subscriber)
+		if r.URL.Query().Get("replay") == "0" {
+			replay = nil
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	writeEvent := func(event string, value any) {
+		bs, _ := marshalRecord(value)
+		_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, bs)
+	}
+
+	for _, record := range replay {
+		writeEvent("result", record)
+	}
+	writeEvent("progress", job.view())
+	flusher.Flush()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case record, opened := <-subscriber:
+			if !opened {
+				// 客户端读取太慢被断开，通知客户端后关闭连接
+				writeEvent("error", map[string]string{"error": "client too slow, results dropped"})
+				flusher.Flush()
+				return
+			}
+			writeEvent("result", record)
+			flusher.Flush()
+		case <-ticker.C:
+			writeEvent("progress", job.view())
+			flusher.Flush()
+		case <-job.done:
+			// 任务结束前的结果都已经在缓冲区中了
+			for drained := false; !drained; {
+				select {
+				case record, opened := <-subscriber:
+					if opened {
+						writeEvent("result", record)
+					} else {
+						drained = true
+					}
+				default:
+					drained = true
+				}
+			}
+			writeEvent("done", job.view())
+			flusher.Flush()
+			return
+		case <-r.Context().Done():
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package enumsubdomain
+
+import (
+	"context"
+	"fmt"
+	"github.com/bytedance/sonic"
+	"net/http"
+	netURL "net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultShodanBaseURL Shodan API 的默认地址
+const DefaultShodanBaseURL = "https://api.shodan.io"
+
+// shodanInterval Shodan 每秒最多请求 1 次
+const shodanInterval = time.Second
+
+// ShodanDomainResponse Shodan dns/domain/{domain} 接口的返回结果，subdomain 为空表示目标本身
+//
+// 请求结果样例：
+//
+//	{
+//	    "domain": "lightless.me",
+//	    "subdomains": ["c1", "ss"],
+//	    "data": [
+//	        {"subdomain": "c1", "type": "A", "value": "43.129.25.182", "ports": [80, 443]},
+//	        {"subdomain": "", "type": "MX", "value": "mx.lightless.me"}
+//	    ],
+//	    "more": false
+//	}
+type ShodanDomainResponse struct {
+	Domain     string   `json:"domain"`
+	Subdomains []string `json:"subdomains"`
+	Data       []struct {
+		Subdomain string `json:"subdomain"`
+		Type      string `json:"type"`
+		Value     string `json:"value"`
+		Ports     []int  `json:"ports"`
+	} `json:"data"`
+	More bool `json:"more"`
+}
+
+// ShodanSource Shodan 数据源，使用 DNS 接口查询子域名，A 和 AAAA 记录会作为服务保存
+type ShodanSource struct {
+	baseURL   string
+	key       string
+	maxPages  int
+	requester *apiRequester
+}
+
+func newShodanSource(args *AppArgs, _ *passiveRun, baseURL string) (PassiveSource, error) {
+	if _, err := netURL.ParseRequestURI(baseURL); err != nil {
+		return nil, fmt.Errorf("shodan base url error: %w", err)
+	}
+	return &ShodanSource{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		key:       args.Credentials["shodan"],
+		maxPages:  args.passiveMaxPages(),
+		requester: newAPIRequester("shodan", shodanInterval),
+	}, nil
+}
+
+func (s *ShodanSource) Name() string {
+	return "shodan"
+}
+
+func (s *ShodanSource) MaxPages() int {
+	return s.maxPages
+}
+
+func (s *ShodanSource) Search(ctx context.Context, target string, page int) (*PassivePage, error) {
+	params := netURL.Values{}
+	params.Set("key", s.key)
+	params.Set("page", strconv.Itoa(page))
+
+	url := fmt.Sprintf("%s/dns/domain/%s?%s", s.baseURL, netURL.PathEscape(target), params.Encode())
+	content, err := s.requester.do(ctx, http.MethodGet, url, nil, nil)
+	if err != nil {
+		if message := apiErrorMessage(err, "error"); message != "" {
+			return nil, &PassiveAPIError{Source: "shodan", Message: message}
+		}
+		return nil, err
+	}
+	response := &ShodanDomainResponse{}
+	if err := sonic.Unmarshal(content, response); err != nil {
+		return nil, fmt.Errorf("error when parse shodan response: %w", err)
+	}
+
+	subdomain := func(prefix string) string {
+		if prefix == "" {
+			return target
+		}
+		return prefix + "." + target
+	}
+	result := &PassivePage{Hosts: make([]PassiveHost, 0, len(response.Subdomains)+len(response.Data)), More: response.More}
+	for _, prefix := range response.Subdomains {
+		result.Hosts = append(result.Hosts, PassiveHost{Host: subdomain(prefix)})
+	}
+	for _, record := range response.Data {
+		if record.Type != "A" && record.Type != "AAAA" {
+			continue
+		}
+		if len(record.Ports) == 0 {
+			result.Hosts = append(result.Hosts, PassiveHost{Host: subdomain(record.Subdomain), Service: ServiceRecord{IP: record.Value}})
+		}
+		for _, port := range record.Ports {
+			result.Hosts = append(result.Hosts, PassiveHost{Host: subdomain(record.Subdomain), Service: ServiceRecord{IP: record.Value, Port: port}})
+		}
+	}
+	return result, nil
+}
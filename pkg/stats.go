@@ -0,0 +1,131 @@
+package enumsubdomain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ScanStats 扫描过程中的计数器，会被多个引擎并发更新
+type ScanStats struct {
+	TasksTotal     atomic.Uint64 // 预估的爆破任务总数，用于显示进度，不包括被动数据源的结果
+	TasksGenerated atomic.Uint64 // TaskBuilderEngine 生成的任务数
+	TasksResolved  atomic.Uint64 // BruteEngine 完成解析的域名数
+	ResultsFound   atomic.Uint64 // ResultEngine 去重后接收的结果数
+	Resolvers      ResolverStats // 每个 nameserver 的查询情况
+}
+
+// ResolverStats 按 nameserver 统计的查询次数、失败次数和耗时
+type ResolverStats struct {
+	mutex     sync.Mutex
+	resolvers map[string]*resolverCounter
+}
+
+type resolverCounter struct {
+	queries atomic.Uint64
+	errors  atomic.Uint64
+	latency atomic.Int64 // 所有成功查询的总耗时，单位纳秒
+}
+
+// ResolverHealth 一个 nameserver 的健康状况
+type ResolverHealth struct {
+	Nameserver   string  `json:"nameserver"`
+	Queries      uint64  `json:"queries"`
+	Errors       uint64  `json:"errors"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+// record 记录一次查询，可以在 nil 上调用
+func (s *ResolverStats) record(nameserver string, latency time.Duration, err error) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.resolvers == nil {
+		s.resolvers = make(map[string]*resolverCounter)
+	}
+	counter, ok := s.resolvers[nameserver]
+	if !ok {
+		counter = &resolverCounter{}
+		s.resolvers[nameserver] = counter
+	}
+	s.mutex.Unlock()
+
+	counter.queries.Add(1)
+	if err != nil {
+		counter.errors.Add(1)
+	} else {
+		counter.latency.Add(int64(latency))
+	}
+}
+
+// Snapshot 返回所有 nameserver 当前的健康状况，按 nameserver 排序
+func (s *ResolverStats) Snapshot() []ResolverHealth {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	health := make([]ResolverHealth, 0, len(s.resolvers))
+	for nameserver, counter := range s.resolvers {
+		item := ResolverHealth{
+			Nameserver: nameserver,
+			Queries:    counter.queries.Load(),
+			Errors:     counter.errors.Load(),
+		}
+		if succeeded := item.Queries - item.Errors; succeeded > 0 {
+			item.AvgLatencyMs = float64(counter.latency.Load()) / float64(succeeded) / float64(time.Millisecond)
+		}
+		health = append(health, item)
+	}
+	sort.Slice(health, func(i, j int) bool {
+		return health[i].Nameserver < health[j].Nameserver
+	})
+	return health
+}
+
+// ScanSummary 一次扫描结束（或被中断）后的汇总信息
+type ScanSummary struct {
+	Targets        []string
+	Interrupted    bool
+	Elapsed        time.Duration
+	TasksGenerated uint64
+	TasksResolved  uint64
+	ResultsFound   uint64
+	Outputs        []string
+	CheckpointFile string
+}
+
+func newScanSummary(args *AppArgs, stats *ScanStats, startTime time.Time, interrupted bool) *ScanSummary {
+	return &ScanSummary{
+		Targets:        args.Targets,
+		Interrupted:    interrupted,
+		Elapsed:        time.Since(startTime),
+		TasksGenerated: stats.TasksGenerated.Load(),
+		TasksResolved:  stats.TasksResolved.Load(),
+		ResultsFound:   stats.ResultsFound.Load(),
+		Outputs:        args.Outputs,
+		CheckpointFile: args.CheckpointFile,
+	}
+}
+
+func (s *ScanSummary) String() string {
+	status := "finished"
+	if s.Interrupted {
+		status = "interrupted"
+	}
+	targets := strings.Join(s.Targets, ",")
+	if len(s.Targets) > 3 {
+		targets = fmt.Sprintf("%d targets", len(s.Targets))
+	}
+	summary := fmt.Sprintf(
+		"Scan %s for %s in %s, tasks generated: %d, resolved: %d, found: %d, output: %s",
+		status, targets, s.Elapsed.Round(time.Millisecond),
+		s.TasksGenerated, s.TasksResolved, s.ResultsFound, strings.Join(s.Outputs, ","),
+	)
+	if s.Interrupted && s.CheckpointFile != "" {
+		summary += fmt.Sprintf(", resume with: --resume %s", s.CheckpointFile)
+	}
+	return summary
+}
@@ -0,0 +1,95 @@
+package enumsubdomain
+
+import (
+	"bufio"
+	"fmt"
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ReadTargets 按行读取目标域名，忽略空行和 # 开头的注释
+func ReadTargets(reader io.Reader) ([]string, error) {
+	targets := make([]string, 0)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		targets = append(targets, line)
+	}
+	return targets, scanner.Err()
+}
+
+// ReadTargetsFile 从文件中读取目标域名，文件名为 - 时从 stdin 读取
+func ReadTargetsFile(filename string) ([]string, error) {
+	if filename == "-" {
+		return ReadTargets(os.Stdin)
+	}
+
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't open targets file %s: %w", filename, err)
+	}
+	defer func() { _ = fp.Close() }()
+	return ReadTargets(fp)
+}
+
+// targetProfile 校验并转换国际化域名，要求每个 label 只包含字母、数字和连字符，且长度符合 DNS 限制
+var targetProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.StrictDomainName(true),
+	idna.VerifyDNSLength(true),
+)
+
+// NormalizeTarget 把用户输入的目标整理成标准的根域名
+// 会去掉 scheme、路径、端口和结尾的点，转换为小写，国际化域名转换为 punycode，
+// 并拒绝 IP 地址以及 com、com.cn 这类公共后缀
+func NormalizeTarget(raw string) (string, error) {
+	target := strings.TrimSpace(raw)
+
+	// 带 scheme 的按 URL 解析，否则手动去掉路径、查询参数和用户信息
+	if strings.Contains(target, "://") {
+		parsed, err := url.Parse(target)
+		if err != nil {
+			return "", fmt.Errorf("target %s format error: %w", raw, err)
+		}
+		target = parsed.Hostname()
+	} else {
+		if idx := strings.IndexAny(target, "/?#"); idx >= 0 {
+			target = target[:idx]
+		}
+		if idx := strings.LastIndex(target, "@"); idx >= 0 {
+			target = target[idx+1:]
+		}
+		if host, _, err := net.SplitHostPort(target); err == nil {
+			target = host
+		}
+	}
+
+	target = strings.TrimRight(target, ".")
+	if target == "" {
+		return "", fmt.Errorf("target %s is empty", raw)
+	}
+	if net.ParseIP(target) != nil {
+		return "", fmt.Errorf("target %s is an IP address, not a domain", raw)
+	}
+
+	target, err := targetProfile.ToASCII(strings.ToLower(target))
+	if err != nil {
+		return "", fmt.Errorf("target %s is not a valid domain: %w", raw, err)
+	}
+
+	// 公共后缀本身不能作为目标，例如 com、co.uk
+	if _, err := publicsuffix.EffectiveTLDPlusOne(target); err != nil {
+		return "", fmt.Errorf("target %s is a public suffix, not a registrable domain", raw)
+	}
+
+	return target, nil
+}
@@ -2,6 +2,7 @@ package enumsubdomain
 
 import (
 	"bufio"
+	"context"
 	"github.com/lightless233/enum-subdomain-go/pkg/resources"
 	"io"
 	"os"
@@ -11,23 +12,30 @@ import (
 )
 
 type TaskBuilderEngine struct {
-	mainWG        *sync.WaitGroup
-	waitGroup     *sync.WaitGroup
-	bruteTaskChan chan string
-	fofaTaskChan  chan string
-	alphaTable    []string
-	appArgs       *AppArgs
+	ctx             context.Context
+	mainWG          *sync.WaitGroup
+	waitGroup       *sync.WaitGroup
+	bruteTaskChan   chan *bruteTask
+	passiveTaskChan chan *passiveTask
+	alphaTable      []string
+	appArgs         *AppArgs
+	stats           *ScanStats
+	checkpointer    *Checkpointer
+	seq             uint64
 }
 
-func NewTaskBuilderEngine(appArgs *AppArgs, mainWG *sync.WaitGroup, bruteTaskChan, fofaTaskChan chan string) *TaskBuilderEngine {
+func NewTaskBuilderEngine(ctx context.Context, appArgs *AppArgs, stats *ScanStats, checkpointer *Checkpointer, mainWG *sync.WaitGroup, bruteTaskChan chan *bruteTask, passiveTaskChan chan *passiveTask) *TaskBuilderEngine {
 	var wg sync.WaitGroup
 	return &TaskBuilderEngine{
-		mainWG:        mainWG,
-		waitGroup:     &wg,
-		bruteTaskChan: bruteTaskChan,
-		fofaTaskChan:  fofaTaskChan,
-		alphaTable:    BuildAlphaTable(),
-		appArgs:       appArgs,
+		ctx:             ctx,
+		mainWG:          mainWG,
+		waitGroup:       &wg,
+		bruteTaskChan:   bruteTaskChan,
+		passiveTaskChan: passiveTaskChan,
+		alphaTable:      BuildAlphaTable(),
+		appArgs:         appArgs,
+		stats:           stats,
+		checkpointer:    checkpointer,
 	}
 }
 
@@ -35,7 +43,7 @@ func (e *TaskBuilderEngine) Run() {
 	defer func() {
 		e.mainWG.Done()
 		close(e.bruteTaskChan)
-		close(e.fofaTaskChan)
+		close(e.passiveTaskChan)
 	}()
 	e.waitGroup.Add(1)
 	go e.worker()
@@ -45,78 +53,142 @@ func (e *TaskBuilderEngine) Run() {
 func (e *TaskBuilderEngine) worker() {
 	defer e.waitGroup.Done()
 
-	// 遍历 Technicals，根据指定的 tech 生成任务
-	for _, tech := range e.appArgs.Technicals {
-		logger.Infof("Build task for technical %s", tech)
-		if tech == "D" {
-			// 字典的
-			if !e.appArgs.HasWildcard {
-				e.buildDictTask()
+	// 恢复扫描时，cursor 之前的任务都已经测试过了
+	resumeCursor := e.checkpointer.resumeCursor()
+
+	// 预估任务总数，用于显示进度
+	e.stats.TasksTotal.Store(e.estimateTasks(resumeCursor))
+
+	// 依次为每个目标生成任务，所有目标共享同一组 BruteEngine
+	for targetIdx, target := range e.appArgs.Targets {
+		// 遍历 Technicals，根据指定的 tech 生成任务
+		for stage, tech := range e.appArgs.Technicals {
+			// 收到中断信号后不再生成新的任务
+			if e.ctx.Err() != nil {
+				logger.Infof("Task building interrupted.")
+				return
+			}
+
+			// 恢复扫描时跳过已经完成的阶段，被动数据源的完成情况单独记录
+			position := taskCursor{Target: targetIdx, Stage: stage, Technical: tech}
+			if resumeCursor != nil && !isPassiveTechnical(tech) &&
+				(targetIdx < resumeCursor.Target || targetIdx == resumeCursor.Target && stage < resumeCursor.Stage) {
+				logger.Infof("Technical %s for %s already finished in checkpoint, skip it.", tech, target)
+				continue
 			}
-		} else if tech == "L" {
-			// 长度爆破的
-			if !e.appArgs.HasWildcard {
-				e.buildBruteLengthTask()
+
+			logger.Infof("Build task for technical %s, target: %s", tech, target)
+			if tech == "D" {
+				// 字典的
+				if !e.appArgs.hasWildcard(target) {
+					e.buildDictTask(target, position)
+				}
+			} else if tech == "L" {
+				// 长度爆破的
+				if !e.appArgs.hasWildcard(target) {
+					e.buildBruteLengthTask(target, position)
+				}
+			} else if isPassiveTechnical(tech) {
+				// 被动数据源收集的
+				if e.checkpointer.resumePassiveDone(tech, target) {
+					logger.Infof("Technical %s for %s already finished in checkpoint, skip it.", tech, target)
+					continue
+				}
+				e.buildPassiveTask(tech, target)
+			} else {
+				logger.Warnf("Unknown technical: %s, skip it.", tech)
 			}
-		} else if tech == "F" {
-			// FOFA 收集的
-			e.buildFofaTask()
-		} else {
-			logger.Warnf("Unknown technical: %s, skip it.", tech)
 		}
 	}
 }
 
-// buildDictTask 从字典模式构建任务
-func (e *TaskBuilderEngine) buildDictTask() {
-	if e.appArgs.DictFile != "" {
-		fp, err := os.Open(e.appArgs.DictFile)
-		defer func() { _ = fp.Close() }()
-		if err != nil {
-			logger.Warnf("Error when reading dict file %s, error: %+v", e.appArgs.DictFile, err)
-			return
+// estimateTasks 预估需要生成的爆破任务总数，恢复扫描时只计算剩下的部分，被动数据源的任务数无法预估
+func (e *TaskBuilderEngine) estimateTasks(resumeCursor *taskCursor) uint64 {
+	var total uint64
+	for targetIdx, target := range e.appArgs.Targets {
+		if e.appArgs.hasWildcard(target) {
+			continue
 		}
-
-		// 按行读文件
-		br := bufio.NewReader(fp)
-		for {
-			line, err := br.ReadString('\n')
-			if err != nil && err != io.EOF {
-				// 读取过程中遇到了错误
-				logger.Warnf("Error when reading line in dict file %s, error: %+v", e.appArgs.DictFile, err)
-				break
+		for stage, tech := range e.appArgs.Technicals {
+			var resumed *taskCursor
+			if resumeCursor != nil {
+				if targetIdx < resumeCursor.Target || targetIdx == resumeCursor.Target && stage < resumeCursor.Stage {
+					continue
+				} else if targetIdx == resumeCursor.Target && stage == resumeCursor.Stage {
+					resumed = resumeCursor
+				}
 			}
 
-			line = strings.TrimSpace(line)
-			if strings.HasPrefix(line, "#") || line == "" {
-				continue
+			if tech == "D" {
+				total += e.countDictTasks(resumed)
+			} else if tech == "L" {
+				total += e.countBruteLengthTasks(resumed)
 			}
+		}
+	}
+	return total
+}
 
-			e.bruteTaskChan <- line
-			logger.Debugf("Add task %s to chan", line)
+// openDict 打开字典文件，没有指定字典时使用内置字典
+func (e *TaskBuilderEngine) openDict() (io.ReadCloser, error) {
+	if e.appArgs.DictFile != "" {
+		return os.Open(e.appArgs.DictFile)
+	}
+	return io.NopCloser(strings.NewReader(resources.DefaultDict)), nil
+}
 
-			if err == io.EOF {
-				break
-			}
+// countDictTasks 统计字典中有效的行数，resumed 不为空时只统计它之后的行
+func (e *TaskBuilderEngine) countDictTasks(resumed *taskCursor) uint64 {
+	reader, err := e.openDict()
+	if err != nil {
+		return 0
+	}
+	defer func() { _ = reader.Close() }()
+
+	var count, lineNo uint64
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") && (resumed == nil || lineNo > resumed.Offset) {
+			count++
 		}
-	} else {
-		// 使用内置字典
-		innerDict := strings.Split(resources.DefaultDict, "\n")
-		logger.Infof("Load inner default dict, count: %d", len(innerDict))
-		for _, task := range innerDict {
-			task = strings.TrimSpace(task)
-			if task == "" || strings.HasPrefix(task, "#") {
+	}
+	return count
+}
+
+// countBruteLengthTasks 统计长度爆破的任务数，不以 - 开头或结尾的才是有效的任务，resumed 不为空时只统计它之后的部分
+func (e *TaskBuilderEngine) countBruteLengthTasks(resumed *taskCursor) uint64 {
+	minLength, maxLength := e.bruteLengthRange()
+	tableSize := len(e.alphaTable)
+	var total uint64
+	for length := int(minLength); length <= int(maxLength); length++ {
+		var start uint64
+		if resumed != nil {
+			if length < resumed.Length {
 				continue
+			} else if length == resumed.Length {
+				start = resumed.Offset + 1
 			}
+		}
 
-			e.bruteTaskChan <- task
+		size := KeyspaceSize(tableSize, length)
+		if start >= size {
+			continue
+		}
+		// 首尾各有一个字符不能是 -，长度为 1 时只有一个字符
+		ratio := float64(tableSize-1) / float64(tableSize)
+		if length > 1 {
+			ratio *= ratio
 		}
+		total += uint64(float64(size-start) * ratio)
 	}
+	return total
 }
 
-// buildBruteLengthTask 从爆破模式构建任务
-func (e *TaskBuilderEngine) buildBruteLengthTask() {
-	// 先解析 brute-length 参数，如果是单个数字，直接跑，如果是区间，则依次生成
+// bruteLengthRange 解析 brute-length 参数，如果是单个数字，最小和最大长度相同
+func (e *TaskBuilderEngine) bruteLengthRange() (uint64, uint64) {
 	var minLength, maxLength uint64
 	if strings.Contains(e.appArgs.BruteLength, "-") {
 		// 区间
@@ -128,47 +200,106 @@ func (e *TaskBuilderEngine) buildBruteLengthTask() {
 		minLength = l
 		maxLength = l
 	}
+	return minLength, maxLength
+}
+
+// buildDictTask 从字典模式构建任务
+func (e *TaskBuilderEngine) buildDictTask(target string, position taskCursor) {
+	reader, err := e.openDict()
+	if err != nil {
+		logger.Warnf("Error when reading dict file %s, error: %+v", e.appArgs.DictFile, err)
+		return
+	}
+	defer func() { _ = reader.Close() }()
+	if e.appArgs.DictFile == "" {
+		// 使用内置字典
+		logger.Infof("Load inner default dict, count: %d", strings.Count(resources.DefaultDict, "\n")+1)
+	}
 
-	for i := minLength; i <= maxLength; i++ {
-		logger.Debug("Start build task for length ", i)
-		for _, item := range product(e.alphaTable, int(i)) {
-			task := strings.Join(item, "")
-			if !strings.HasSuffix(task, "-") && !strings.HasPrefix(task, "-") {
-				e.bruteTaskChan <- task
+	// 按行读字典，行号作为 checkpoint 中记录的位置
+	resumeCursor := e.checkpointer.resumeCursor()
+	br := bufio.NewReader(reader)
+	var lineNo uint64
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			// 读取过程中遇到了错误
+			logger.Warnf("Error when reading line in dict file %s, error: %+v", e.appArgs.DictFile, err)
+			break
+		}
+		lineNo++
+
+		line = strings.TrimSpace(line)
+		cursor := position
+		cursor.Offset = lineNo
+		if line != "" && !strings.HasPrefix(line, "#") && !resumeCursor.covers(cursor) {
+			if !e.sendBruteTask(line, target, cursor) {
+				return
 			}
+			logger.Debugf("Add task %s to chan", line)
+		}
+
+		if err == io.EOF {
+			break
 		}
-		logger.Debugf("Build task for length %d done.", i)
 	}
 }
 
-// buildFofaTask 创建一个 fofa 任务
-func (e *TaskBuilderEngine) buildFofaTask() {
-	// fofa 只要发个通知就行了
-	e.fofaTaskChan <- "fofa"
-}
+// buildBruteLengthTask 从爆破模式构建任务
+func (e *TaskBuilderEngine) buildBruteLengthTask(target string, position taskCursor) {
+	// 先解析 brute-length 参数，如果是单个数字，直接跑，如果是区间，则依次生成
+	minLength, maxLength := e.bruteLengthRange()
 
-func product(a []string, k int) [][]string {
-	indexes := make([]int, k)
-	var ps [][]string
+	resumeCursor := e.checkpointer.resumeCursor()
+	for i := minLength; i <= maxLength; i++ {
+		length := int(i)
 
-	for indexes != nil {
-		p := make([]string, k)
-		for i, x := range indexes {
-			p[i] = a[x]
+		// 恢复扫描时，从 checkpoint 记录的位置之后继续生成
+		var start uint64
+		if resumeCursor != nil && resumeCursor.Target == position.Target && resumeCursor.Stage == position.Stage {
+			if length < resumeCursor.Length {
+				continue
+			} else if length == resumeCursor.Length {
+				start = resumeCursor.Offset + 1
+			}
 		}
 
-		for i := len(indexes) - 1; i >= 0; i-- {
-			indexes[i]++
-			if indexes[i] < len(a) {
-				break
-			}
-			indexes[i] = 0
-			if i <= 0 {
-				indexes = nil
-				break
+		logger.Debug("Start build task for length ", i)
+		total := KeyspaceSize(len(e.alphaTable), length)
+		for idx := start; idx < total; idx++ {
+			task := KeyspaceItem(e.alphaTable, length, idx)
+			if !strings.HasSuffix(task, "-") && !strings.HasPrefix(task, "-") {
+				cursor := position
+				cursor.Length = length
+				cursor.Offset = idx
+				if !e.sendBruteTask(task, target, cursor) {
+					return
+				}
 			}
 		}
-		ps = append(ps, p)
+		logger.Debugf("Build task for length %d done.", i)
+	}
+}
+
+// buildPassiveTask 创建一个被动数据源的任务
+func (e *TaskBuilderEngine) buildPassiveTask(tech, target string) {
+	// 被动数据源只要把目标发过去就行了
+	select {
+	case e.passiveTaskChan <- &passiveTask{technical: tech, target: target}:
+	case <-e.ctx.Done():
+	}
+}
+
+// sendBruteTask 投递一个爆破任务，如果 ctx 已经被取消则返回 false，调用方应停止生成任务
+func (e *TaskBuilderEngine) sendBruteTask(name, target string, cursor taskCursor) bool {
+	task := &bruteTask{name: name, target: target, seq: e.seq, cursor: cursor}
+	select {
+	case e.bruteTaskChan <- task:
+		e.seq++
+		e.stats.TasksGenerated.Add(1)
+		metricTasksGenerated.Inc()
+		return true
+	case <-e.ctx.Done():
+		return false
 	}
-	return ps
 }
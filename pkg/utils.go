@@ -1,6 +1,10 @@
 package enumsubdomain
 
-import "math/rand"
+import (
+	"math"
+	"math/rand"
+	"strings"
+)
 
 const LETTERS = "abcdefghijklmnopqrstuvwxyz0123456789"
 
@@ -23,3 +27,26 @@ func BuildAlphaTable() []string {
 	table = append(table, "-")
 	return table
 }
+
+// KeyspaceSize 计算长度为 length 的爆破 keyspace 大小，溢出时返回 math.MaxUint64
+func KeyspaceSize(tableSize, length int) uint64 {
+	var size uint64 = 1
+	for i := 0; i < length; i++ {
+		if size > math.MaxUint64/uint64(tableSize) {
+			return math.MaxUint64
+		}
+		size *= uint64(tableSize)
+	}
+	return size
+}
+
+// KeyspaceItem 返回 keyspace 中第 idx 个字符串，按 table 顺序排列，最后一位变化最快
+func KeyspaceItem(table []string, length int, idx uint64) string {
+	parts := make([]string, length)
+	base := uint64(len(table))
+	for i := length - 1; i >= 0; i-- {
+		parts[i] = table[idx%base]
+		idx /= base
+	}
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,119 @@
+package enumsubdomain
+
+import (
+	"context"
+	"fmt"
+	"github.com/bytedance/sonic"
+	"net/http"
+	netURL "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultVirusTotalBaseURL VirusTotal API 的默认地址
+const DefaultVirusTotalBaseURL = "https://www.virustotal.com"
+
+const (
+	// virusTotalPageSize VirusTotal relationships 接口每页的结果数量，接口允许的最大值为 40
+	virusTotalPageSize = 40
+	// virusTotalInterval 公开 API 每分钟最多请求 4 次
+	virusTotalInterval = 15 * time.Second
+)
+
+// VirusTotalSubdomainsResponse VirusTotal domains/{domain}/relationships/subdomains 接口的返回结果
+//
+// 请求结果样例：
+//
+//	{
+//	    "data": [
+//	        {"id": "c1.lightless.me", "type": "domain"},
+//	        {"id": "ss.lightless.me", "type": "domain"}
+//	    ],
+//	    "meta": {"count": 2, "cursor": "eyJsaW1pdCI6IDQwLCAib2Zmc2V0IjogNDB9"},
+//	    "links": {"self": "...", "next": "..."}
+//	}
+type VirusTotalSubdomainsResponse struct {
+	Data []struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	} `json:"data"`
+	Meta struct {
+		Count  int    `json:"count"`
+		Cursor string `json:"cursor"` // 下一页的游标，没有更多结果时为空
+	} `json:"meta"`
+}
+
+// VirusTotalSource VirusTotal 数据源，查询域名关系中的子域名
+// 接口使用游标分页，按目标保存下一页的游标，请求第一页时重置
+type VirusTotalSource struct {
+	baseURL   string
+	key       string
+	maxPages  int
+	requester *apiRequester
+
+	mutex   sync.Mutex
+	cursors map[string]string
+}
+
+func newVirusTotalSource(args *AppArgs, _ *passiveRun, baseURL string) (PassiveSource, error) {
+	if _, err := netURL.ParseRequestURI(baseURL); err != nil {
+		return nil, fmt.Errorf("virustotal base url error: %w", err)
+	}
+	return &VirusTotalSource{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		key:       args.Credentials["virustotal"],
+		maxPages:  args.passiveMaxPages(),
+		requester: newAPIRequester("virustotal", virusTotalInterval),
+		cursors:   make(map[string]string),
+	}, nil
+}
+
+func (s *VirusTotalSource) Name() string {
+	return "virustotal"
+}
+
+func (s *VirusTotalSource) MaxPages() int {
+	return s.maxPages
+}
+
+func (s *VirusTotalSource) Search(ctx context.Context, target string, page int) (*PassivePage, error) {
+	params := netURL.Values{}
+	params.Set("limit", strconv.Itoa(virusTotalPageSize))
+	if page > 1 {
+		s.mutex.Lock()
+		cursor := s.cursors[target]
+		s.mutex.Unlock()
+		if cursor == "" {
+			return &PassivePage{}, nil
+		}
+		params.Set("cursor", cursor)
+	}
+	header := http.Header{}
+	header.Set("x-apikey", s.key)
+
+	url := fmt.Sprintf("%s/api/v3/domains/%s/relationships/subdomains?%s", s.baseURL, netURL.PathEscape(target), params.Encode())
+	content, err := s.requester.do(ctx, http.MethodGet, url, header, nil)
+	if err != nil {
+		// 错误格式为 {"error": {"code": "WrongCredentialsError", "message": "..."}}
+		if message := apiErrorMessage(err, "error"); message != "" {
+			return nil, &PassiveAPIError{Source: "virustotal", Message: message}
+		}
+		return nil, err
+	}
+	response := &VirusTotalSubdomainsResponse{}
+	if err := sonic.Unmarshal(content, response); err != nil {
+		return nil, fmt.Errorf("error when parse virustotal response: %w", err)
+	}
+
+	s.mutex.Lock()
+	s.cursors[target] = response.Meta.Cursor
+	s.mutex.Unlock()
+
+	result := &PassivePage{Hosts: make([]PassiveHost, 0, len(response.Data)), More: response.Meta.Cursor != ""}
+	for _, item := range response.Data {
+		result.Hosts = append(result.Hosts, PassiveHost{Host: item.ID})
+	}
+	return result, nil
+}
@@ -0,0 +1,188 @@
+package enumsubdomain
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	netURL "net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWaybackBaseURL Wayback Machine CDX API 的默认地址
+const DefaultWaybackBaseURL = "https://web.archive.org"
+
+// waybackInterval Wayback Machine 对频繁的请求会直接断开连接，两次请求之间至少间隔 1s
+const waybackInterval = time.Second
+
+// ArchivedURL 一个域名在网页存档中的所有历史 URL
+type ArchivedURL struct {
+	Target string   `json:"target"`
+	Host   string   `json:"host"`
+	URLs   []string `json:"urls"`
+}
+
+// ArchivedURLs 收集网页存档中每个域名的历史 URL，所有方法都可以在 nil 上调用
+type ArchivedURLs struct {
+	mutex sync.Mutex
+	hosts []*ArchivedURL
+	index map[string]*ArchivedURL
+}
+
+func NewArchivedURLs() *ArchivedURLs {
+	return &ArchivedURLs{index: make(map[string]*ArchivedURL)}
+}
+
+// add 记录域名的一个历史 URL
+func (a *ArchivedURLs) add(target, host, url string) {
+	if a == nil {
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	key := target + "|" + host
+	archived, ok := a.index[key]
+	if !ok {
+		archived = &ArchivedURL{Target: target, Host: host}
+		a.index[key] = archived
+		a.hosts = append(a.hosts, archived)
+	}
+	if !slices.Contains(archived.URLs, url) {
+		archived.URLs = append(archived.URLs, url)
+	}
+}
+
+// List 返回所有域名的历史 URL，按发现的顺序排列
+func (a *ArchivedURLs) List() []*ArchivedURL {
+	if a == nil {
+		return nil
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return slices.Clone(a.hosts)
+}
+
+// WriteFile 把每个域名的历史 URL 以 JSON Lines 格式写入文件
+func (a *ArchivedURLs) WriteFile(filename string) error {
+	fp, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("can't open wayback output file %s: %w", filename, err)
+	}
+	for _, archived := range a.List() {
+		bs, err := marshalRecord(archived)
+		if err != nil {
+			_ = fp.Close()
+			return err
+		}
+		if _, err := fp.Write(append(bs, '\n')); err != nil {
+			_ = fp.Close()
+			return err
+		}
+	}
+	return fp.Close()
+}
+
+// WaybackSource 网页存档数据源，查询兼容 Wayback Machine CDX API 的服务中 *.target 的 URL
+// 不需要凭据，可以通过 --source-base-url wayback=... 使用本地的存档镜像
+type WaybackSource struct {
+	baseURL   string
+	maxPages  int
+	requester *apiRequester
+	archived  *ArchivedURLs
+
+	mutex    sync.Mutex
+	numPages map[string]int // 每个目标的总页数，请求第一页时查询
+}
+
+func newWaybackSource(args *AppArgs, run *passiveRun, baseURL string) (PassiveSource, error) {
+	if _, err := netURL.ParseRequestURI(baseURL); err != nil {
+		return nil, fmt.Errorf("wayback base url error: %w", err)
+	}
+	return &WaybackSource{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		maxPages:  args.passiveMaxPages(),
+		requester: newAPIRequester("wayback", waybackInterval),
+		archived:  run.archivedURLs,
+		numPages:  make(map[string]int),
+	}, nil
+}
+
+func (s *WaybackSource) Name() string {
+	return "wayback"
+}
+
+func (s *WaybackSource) MaxPages() int {
+	return s.maxPages
+}
+
+// cdx 请求 CDX 接口，只返回 original 字段，每行一个 URL
+func (s *WaybackSource) cdx(ctx context.Context, target string, extra netURL.Values) ([]byte, error) {
+	params := netURL.Values{}
+	params.Set("url", "*."+target)
+	params.Set("fl", "original")
+	params.Set("collapse", "urlkey")
+	for name, values := range extra {
+		params[name] = values
+	}
+	return s.requester.do(ctx, http.MethodGet, s.baseURL+"/cdx/search/cdx?"+params.Encode(), nil, nil)
+}
+
+func (s *WaybackSource) Search(ctx context.Context, target string, page int) (*PassivePage, error) {
+	// CDX 的分页从 0 开始，先查询总页数，不支持分页的镜像只有一页
+	if page == 1 {
+		numPages := 1
+		content, err := s.cdx(ctx, target, netURL.Values{"showNumPages": {"true"}})
+		if err != nil {
+			return nil, err
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(string(content))); err == nil && n > 0 {
+			numPages = n
+		}
+		s.mutex.Lock()
+		s.numPages[target] = numPages
+		s.mutex.Unlock()
+	}
+	s.mutex.Lock()
+	numPages := s.numPages[target]
+	s.mutex.Unlock()
+
+	params := netURL.Values{}
+	if numPages > 1 {
+		params.Set("page", strconv.Itoa(page-1))
+	}
+	content, err := s.cdx(ctx, target, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 同一个域名通常有大量的 URL，每页中的域名只保留一次
+	result := &PassivePage{More: page < numPages}
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		url := strings.TrimSpace(scanner.Text())
+		host := trimHost(url)
+		if host == "" {
+			continue
+		}
+		if inScope(host, target) {
+			s.archived.add(target, host, url)
+		}
+		if !seen[host] {
+			seen[host] = true
+			result.Hosts = append(result.Hosts, PassiveHost{Host: host})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error when parse wayback response: %w", err)
+	}
+	return result, nil
+}
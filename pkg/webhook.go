@@ -0,0 +1,138 @@
+package enumsubdomain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WebhookTypes 支持的 webhook 消息格式
+var WebhookTypes = []string{"json", "slack", "dingtalk"}
+
+// webhookMaxEntries 聊天消息中最多列出的子域名数量，超出的只显示数量，完整的列表见 json 格式或者 --diff-output
+const webhookMaxEntries = 50
+
+// WebhookNotifier 把对比结果 POST 到 webhook
+type WebhookNotifier struct {
+	url        string
+	kind       string
+	httpClient *http.Client
+}
+
+func NewWebhookNotifier(url, kind string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:        url,
+		kind:       kind,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// webhookPayload json 格式的消息内容
+type webhookPayload struct {
+	Targets  []string     `json:"targets"`
+	Baseline string       `json:"baseline"`
+	Partial  bool         `json:"partial"`
+	New      []*DiffEntry `json:"new"`
+	Changed  []*DiffEntry `json:"changed"`
+	Removed  []*DiffEntry `json:"removed"`
+	SentAt   time.Time    `json:"sent_at"`
+}
+
+// Notify 发送对比结果，只有存在新增、变化或者消失的子域名时才会发送
+func (n *WebhookNotifier) Notify(ctx context.Context, targets []string, report *DiffReport) error {
+	if report == nil || !report.HasChanges() {
+		return nil
+	}
+
+	var payload any
+	switch n.kind {
+	case "slack":
+		payload = map[string]string{"text": n.buildText(targets, report, false)}
+	case "dingtalk":
+		payload = map[string]any{
+			"msgtype": "markdown",
+			"markdown": map[string]string{
+				"title": fmt.Sprintf("Subdomain changes for %s", strings.Join(targets, ",")),
+				"text":  n.buildText(targets, report, true),
+			},
+		}
+	default:
+		payload = &webhookPayload{
+			Targets:  targets,
+			Baseline: report.Baseline,
+			Partial:  report.Partial,
+			New:      report.New,
+			Changed:  report.Changed,
+			Removed:  report.Removed,
+			SentAt:   time.Now(),
+		}
+	}
+
+	bs, err := marshalRecord(payload)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(bs))
+	if err != nil {
+		return fmt.Errorf("webhook url error: %w", redactError(err))
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := n.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("error when sending webhook: %w", redactError(err))
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	// 钉钉的错误通过 HTTP 200 + errcode 返回
+	if n.kind == "dingtalk" {
+		var result struct {
+			ErrCode int    `json:"errcode"`
+			ErrMsg  string `json:"errmsg"`
+		}
+		if err := json.Unmarshal(body, &result); err == nil && result.ErrCode != 0 {
+			return fmt.Errorf("dingtalk webhook error %d: %s", result.ErrCode, result.ErrMsg)
+		}
+	}
+	return nil
+}
+
+// buildText 生成聊天工具中显示的文本，markdown 为 true 时使用钉钉的 markdown 格式
+func (n *WebhookNotifier) buildText(targets []string, report *DiffReport, markdown bool) string {
+	var builder strings.Builder
+	title := fmt.Sprintf("Subdomain changes for %s: new: %d, changed: %d, removed: %d",
+		strings.Join(targets, ","), len(report.New), len(report.Changed), len(report.Removed))
+	if markdown {
+		builder.WriteString("### ")
+	}
+	builder.WriteString(title)
+	builder.WriteString("\n")
+
+	entries := report.Entries()
+	for idx, entry := range entries {
+		if idx >= webhookMaxEntries {
+			fmt.Fprintf(&builder, "\n... and %d more", len(entries)-webhookMaxEntries)
+			break
+		}
+		line := fmt.Sprintf("[%s] %s", entry.Status, entry.Domain)
+		if len(entry.Changes) > 0 {
+			line += " " + strings.Join(entry.Changes, "; ")
+		}
+		if markdown {
+			builder.WriteString("\n- " + line)
+		} else {
+			builder.WriteString("\n" + line)
+		}
+	}
+	return builder.String()
+}
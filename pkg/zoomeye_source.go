@@ -0,0 +1,103 @@
+package enumsubdomain
+
+import (
+	"context"
+	"fmt"
+	"github.com/bytedance/sonic"
+	"net/http"
+	netURL "net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultZoomEyeBaseURL ZoomEye API 的默认地址
+const DefaultZoomEyeBaseURL = "https://api.zoomeye.org"
+
+// zoomEyePageSize ZoomEye 子域名接口固定每页返回 30 条结果
+const zoomEyePageSize = 30
+
+// ZoomEyeDomainResponse ZoomEye domain/search 接口的返回结果
+//
+// 请求结果样例（type=1 表示查询子域名）：
+//
+//	{
+//	    "status": 200,
+//	    "total": 2,
+//	    "list": [
+//	        {"name": "c1.lightless.me", "ip": ["43.129.25.182"], "timestamp": "2023-09-01"},
+//	        {"name": "ss.lightless.me", "ip": []}
+//	    ]
+//	}
+type ZoomEyeDomainResponse struct {
+	Status int `json:"status"`
+	Total  int `json:"total"`
+	List   []struct {
+		Name string   `json:"name"`
+		IP   []string `json:"ip"`
+	} `json:"list"`
+}
+
+// ZoomEyeSource ZoomEye 数据源，使用子域名接口查询
+type ZoomEyeSource struct {
+	baseURL   string
+	key       string
+	maxPages  int
+	requester *apiRequester
+}
+
+func newZoomEyeSource(args *AppArgs, _ *passiveRun, baseURL string) (PassiveSource, error) {
+	if _, err := netURL.ParseRequestURI(baseURL); err != nil {
+		return nil, fmt.Errorf("zoomeye base url error: %w", err)
+	}
+	return &ZoomEyeSource{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		key:       args.Credentials["zoomeye"],
+		maxPages:  args.passiveMaxPages(),
+		requester: newAPIRequester("zoomeye", 0),
+	}, nil
+}
+
+func (s *ZoomEyeSource) Name() string {
+	return "zoomeye"
+}
+
+func (s *ZoomEyeSource) MaxPages() int {
+	return s.maxPages
+}
+
+func (s *ZoomEyeSource) Search(ctx context.Context, target string, page int) (*PassivePage, error) {
+	params := netURL.Values{}
+	params.Set("q", target)
+	params.Set("type", "1")
+	params.Set("page", strconv.Itoa(page))
+	header := http.Header{}
+	header.Set("API-KEY", s.key)
+
+	content, err := s.requester.do(ctx, http.MethodGet, s.baseURL+"/domain/search?"+params.Encode(), header, nil)
+	if err != nil {
+		// ZoomEye 的错误使用非 2xx 状态码返回，例如 {"error": "bad_request", "message": "..."}
+		if message := apiErrorMessage(err, "message", "error"); message != "" {
+			return nil, &PassiveAPIError{Source: "zoomeye", Message: message}
+		}
+		return nil, err
+	}
+	response := &ZoomEyeDomainResponse{}
+	if err := sonic.Unmarshal(content, response); err != nil {
+		return nil, fmt.Errorf("error when parse zoomeye response: %w", err)
+	}
+
+	result := &PassivePage{
+		Hosts: make([]PassiveHost, 0, len(response.List)),
+		More:  page*zoomEyePageSize < response.Total,
+	}
+	for _, item := range response.List {
+		// 子域名接口只返回 IP，没有端口和协议
+		if len(item.IP) == 0 {
+			result.Hosts = append(result.Hosts, PassiveHost{Host: item.Name})
+		}
+		for _, ip := range item.IP {
+			result.Hosts = append(result.Hosts, PassiveHost{Host: item.Name, Service: ServiceRecord{IP: ip}})
+		}
+	}
+	return result, nil
+}